@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+const codeDigits = 6
+
+// NewVerificationCode returns a random numeric code to send to the user and
+// the hash of it to persist.
+func NewVerificationCode() (code, hash string, err error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	code = fmt.Sprintf("%0*d", codeDigits, n)
+	return code, HashCode(code), nil
+}
+
+// HashCode returns the hex encoded SHA-256 digest of a one-time code.
+func HashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// CodeMatches compares a submitted code against a stored hash in constant time.
+func CodeMatches(code, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashCode(code)), []byte(hash)) == 1
+}
@@ -0,0 +1,42 @@
+package auth
+
+import model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+
+// Permission is an action on accounts other than the caller's own
+type Permission string
+
+const (
+	// PermissionReadUsers allows reading any user's profile, addresses and ban status
+	PermissionReadUsers Permission = "users.read"
+	// PermissionCheckUsers allows checking any user's ban status and whether an
+	// address belongs to them, without reading their profile
+	PermissionCheckUsers Permission = "users.check"
+	// PermissionManageUsers allows editing any user's profile and addresses and signing them out
+	PermissionManageUsers Permission = "users.manage"
+	// PermissionBanUsers allows banning and unbanning users
+	PermissionBanUsers Permission = "users.ban"
+	// PermissionManageRoles allows granting and revoking roles
+	PermissionManageRoles Permission = "roles.manage"
+	// PermissionAdjustReputation allows recording reputation changes for any user
+	PermissionAdjustReputation Permission = "reputation.adjust"
+)
+
+// rolePermissions lists what each role may do. RoleUser may only act on its own account.
+// RoleService is for other FoodBuddy services, which check bans and addresses
+// before acting for a user and report reputation changes.
+var rolePermissions = map[model.Role][]Permission{
+	model.RoleUser:      nil,
+	model.RoleSupport:   {PermissionReadUsers, PermissionCheckUsers, PermissionManageUsers},
+	model.RoleModerator: {PermissionReadUsers, PermissionCheckUsers, PermissionBanUsers},
+	model.RoleAdmin:     {PermissionReadUsers, PermissionCheckUsers, PermissionManageUsers, PermissionBanUsers, PermissionManageRoles, PermissionAdjustReputation},
+	model.RoleService:   {PermissionCheckUsers, PermissionAdjustReputation},
+}
+
+func roleGrants(role model.Role, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		roles []model.Role
+		perm  Permission
+		want  bool
+	}{
+		{nil, PermissionReadUsers, false},
+		{[]model.Role{model.RoleSupport}, PermissionManageUsers, true},
+		{[]model.Role{model.RoleSupport}, PermissionBanUsers, false},
+		{[]model.Role{model.RoleModerator}, PermissionBanUsers, true},
+		{[]model.Role{model.RoleModerator}, PermissionManageRoles, false},
+		{[]model.Role{model.RoleModerator, model.RoleSupport}, PermissionManageUsers, true},
+		{[]model.Role{model.RoleAdmin}, PermissionManageRoles, true},
+		{[]model.Role{model.RoleService}, PermissionAdjustReputation, true},
+		{[]model.Role{model.RoleService}, PermissionReadUsers, false},
+		{[]model.Role{model.RoleService}, PermissionCheckUsers, true},
+		{[]model.Role{model.RoleModerator}, PermissionCheckUsers, true},
+		{[]model.Role{model.RoleModerator}, PermissionAdjustReputation, false},
+	}
+	for _, tt := range tests {
+		p := &Principal{UserID: "usr_1", Roles: tt.roles}
+		if got := p.Can(tt.perm); got != tt.want {
+			t.Errorf("roles %v Can(%s) = %v, want %v", tt.roles, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalHasImplicitUserRole(t *testing.T) {
+	p := &Principal{UserID: "usr_1"}
+	if !p.HasRole(model.RoleUser) {
+		t.Error("principal without grants lacks the user role")
+	}
+	if p.HasRole(model.RoleAdmin) {
+		t.Error("principal without grants has the admin role")
+	}
+}
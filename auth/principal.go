@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// Principal is the authenticated caller of an RPC
+type Principal struct {
+	UserID    string
+	SessionID string
+	Roles     []model.Role
+}
+
+// HasRole reports whether the caller holds role. Every caller holds RoleUser.
+func (p *Principal) HasRole(role model.Role) bool {
+	if role == model.RoleUser {
+		return true
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether any of the caller's roles grants perm
+func (p *Principal) Can(perm Permission) bool {
+	for _, role := range append([]model.Role{model.RoleUser}, p.Roles...) {
+		if roleGrants(role, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller stored by WithPrincipal
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+const issuer = "foodbuddy-user-service"
+
+// Claims are the JWT claims carried by access tokens issued by the user service.
+type Claims struct {
+	UserID     string `json:"uid"`
+	IsVerified bool   `json:"verified"`
+	IsBanned   bool   `json:"banned"`
+	// SessionID is the session family the token was issued for, empty for
+	// tokens issued by UserLogin.
+	SessionID string `json:"sid,omitempty"`
+	// TokenVersion must match the user's current TokenVersion
+	TokenVersion int `json:"tv,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager signs and validates HS256 access tokens.
+type TokenManager struct {
+	secret []byte
+	expiry time.Duration
+}
+
+// NewTokenManager returns a TokenManager using the given secret and token lifetime.
+func NewTokenManager(secret string, expiry time.Duration) (*TokenManager, error) {
+	if secret == "" {
+		return nil, errors.New("jwt secret key is not configured")
+	}
+	return &TokenManager{secret: []byte(secret), expiry: expiry}, nil
+}
+
+// Generate issues a signed access token for the user and returns it with its expiry time.
+// sessionID ties the token to a session family and may be empty.
+func (m *TokenManager) Generate(user *model.User, sessionID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.expiry)
+
+	claims := Claims{
+		UserID:       user.ID,
+		IsVerified:   user.IsVerified,
+		IsBanned:     user.IsBanned,
+		SessionID:    sessionID,
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %v", model.ErrTokenGeneration, err)
+	}
+	return token, expiresAt, nil
+}
+
+// Parse validates the token signature, issuer and expiry and returns its claims.
+func (m *TokenManager) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid || claims.UserID == "" {
+		return nil, model.ErrInvalidToken
+	}
+	return claims, nil
+}
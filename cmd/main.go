@@ -1,43 +1,297 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	user "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
 	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
 	"github.com/liju-github/FoodBuddyMicroserviceUser/db"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/geocoder"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/health"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/interceptor"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/logging"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/metrics"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
 	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/reputation"
 	"github.com/liju-github/FoodBuddyMicroserviceUser/service"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
-	// Initialize database connection
-	dbConn, err := db.Connect(cfg)
+	// Initialize the structured logger, also used by the standard log package
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
 	if err != nil {
-		log.Fatalf("Database connection failed: %v", err)
+		log.Fatalf("Logger initialization failed: %v", err)
 	}
-	defer db.Close(dbConn)
+	slog.SetDefault(logger)
 
-	// Initialize repository and service
-	userRepo := repository.NewUserRepository(dbConn)
-	userService := service.NewUserService(userRepo)
+	// Schema changes are applied out of band with "migrate <command>"
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, logger, os.Args[2:]); err != nil {
+			logger.Error("migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Roles can be managed without the RPCs, which need an existing admin
+	if len(os.Args) > 1 && os.Args[1] == "roles" {
+		if err := runRoles(cfg, logger, os.Args[2:]); err != nil {
+			logger.Error("role update failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Cancelled on SIGINT or SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, logger); err != nil {
+		logger.Error("user service stopped with error", "error", err)
+		stop()
+		os.Exit(1)
+	}
+	logger.Info("user service stopped")
+}
+
+// run starts the service and blocks until ctx is cancelled or the server fails.
+// Resources are released by deferred calls so they are closed on every path.
+func run(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+	// Initialize tracing before anything that creates spans
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("tracing initialization failed: %w", err)
+	}
+	defer func() {
+		// Flush buffered spans even though ctx is already cancelled on shutdown
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	// Initialize token manager used to sign access tokens
+	tokens, err := auth.NewTokenManager(cfg.JWTSecretKey, service.TokenExpiry)
+	if err != nil {
+		return fmt.Errorf("token manager initialization failed: %w", err)
+	}
+
+	// Initialize geocoder used to locate addresses
+	geo, err := geocoder.New(cfg)
+	if err != nil {
+		return fmt.Errorf("geocoder initialization failed: %w", err)
+	}
+
+	// Reputation tiers are validated up front so a typo fails at startup
+	tiers, err := reputation.ParseTiers(cfg.ReputationTiers)
+	if err != nil {
+		return fmt.Errorf("invalid REPUTATION_TIERS: %w", err)
+	}
+
+	// Initialize mailer used for verification emails
+	mailer, err := notification.NewMailer(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("mailer initialization failed: %w", err)
+	}
+
+	// Initialize repository for the configured storage backend
+	var userRepo repository.UserRepository
+	var dbConn *gorm.DB
+	switch cfg.StorageBackend {
+	case "memory":
+		logger.Warn("using in-memory storage, data will be lost on restart")
+		userRepo = repository.NewMemoryRepository()
+	case "database":
+		dbConn, err = db.Connect(cfg, logger)
+		if err != nil {
+			return fmt.Errorf("database connection failed: %w", err)
+		}
+		defer db.Close(dbConn, logger)
+		if err := db.EnsureMigrated(dbConn); err != nil {
+			return fmt.Errorf("refusing to start: %w", err)
+		}
+		sqlDB, err := dbConn.DB()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve SQL DB instance: %w", err)
+		}
+		if err := metrics.RegisterDBStats(sqlDB, cfg.DBName); err != nil {
+			return fmt.Errorf("failed to register database metrics: %w", err)
+		}
+		userRepo = repository.NewUserRepository(dbConn)
+	default:
+		return fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+
+	// Every repository call gets a span under the RPC that made it
+	userRepo = repository.NewTracingRepository(userRepo)
+
+	// Initialize service
+	userService := service.NewUserService(userRepo, tokens, mailer, geo, tiers, cfg.MaxAddressesPerUser, logger)
 
 	// Start gRPC server
 	listener, err := net.Listen("tcp", ":"+cfg.USERGRPCPort)
 	if err != nil {
-		log.Fatalf("Failed to start listener: %v", err)
+		return fmt.Errorf("failed to start listener: %w", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		// Continues W3C trace contexts from callers and starts a span per RPC
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			interceptor.MetricsUnaryInterceptor(),
+			interceptor.LoggingUnaryInterceptor(logger),
+			interceptor.ErrorUnaryInterceptor(logger),
+			interceptor.TimeoutUnaryInterceptor(cfg.RPCTimeout, cfg.RPCMethodTimeouts),
+			interceptor.AuthUnaryInterceptor(userService, service.PublicMethods),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.MetricsStreamInterceptor(),
+			interceptor.LoggingStreamInterceptor(logger),
+			interceptor.ErrorStreamInterceptor(logger),
+			interceptor.TimeoutStreamInterceptor(cfg.RPCMethodTimeouts),
+			interceptor.AuthStreamInterceptor(userService, service.PublicMethods),
+		),
+	)
 	user.RegisterUserServiceServer(grpcServer, userService)
+	userExtPb.RegisterUserExtServiceServer(grpcServer, userService)
+
+	// Readiness is reported through grpc.health.v1 and follows the database
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	checker := health.NewChecker(healthServer, readinessProbe(dbConn), cfg.HealthCheckInterval, logger,
+		user.UserService_ServiceDesc.ServiceName,
+		userExtPb.UserExtService_ServiceDesc.ServiceName,
+	)
+
+	// Background workers stop when ctx is cancelled and are waited for on return
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	ctx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		checker.Run(ctx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		userService.RunBanSweeper(ctx, cfg.BanSweepInterval)
+	}()
+
+	if cfg.MetricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start metrics listener: %w", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			serveMetrics(ctx, logger, metricsListener)
+		}()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("user service is running", "port", cfg.USERGRPCPort)
+		serveErr <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			return fmt.Errorf("gRPC server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		logger.Info("shutdown signal received, draining in-flight RPCs")
+	}
+
+	// Tell load balancers to stop routing new calls before draining
+	healthServer.Shutdown()
+
+	gracefulStop(grpcServer, logger, cfg.ShutdownTimeout)
+	return nil
+}
+
+// readinessProbe reports the service ready once the database answers pings and
+// its schema is fully migrated. The in-memory backend is always ready.
+func readinessProbe(dbConn *gorm.DB) health.Probe {
+	return func(ctx context.Context) error {
+		if dbConn == nil {
+			return nil
+		}
+		if err := db.Ping(ctx, dbConn); err != nil {
+			return fmt.Errorf("database ping failed: %w", err)
+		}
+		return db.EnsureMigrated(dbConn.WithContext(ctx))
+	}
+}
+
+// serveMetrics serves the Prometheus endpoint on /metrics until ctx is cancelled.
+// Metrics are best effort, so a failing endpoint is logged rather than stopping the service.
+func serveMetrics(ctx context.Context, logger *slog.Logger, listener net.Listener) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("metrics server shutdown failed", "error", err)
+		}
+	}()
+
+	logger.Info("metrics are served", "address", listener.Addr().String(), "path", "/metrics")
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("metrics server failed", "error", err)
+	}
+}
+
+// gracefulStop waits for in-flight RPCs to finish and forcefully closes the
+// remaining connections once timeout has passed
+func gracefulStop(server *grpc.Server, logger *slog.Logger, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 
-	log.Println("User Service is running on gRPC port: " + cfg.USERGRPCPort)
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatalf("gRPC server startup failed: %v", err)
+	select {
+	case <-stopped:
+		logger.Info("gRPC server drained")
+	case <-timer.C:
+		logger.Warn("drain timeout exceeded, closing remaining connections", "timeout", timeout)
+		server.Stop()
+		<-stopped
 	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/db"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [n]        roll back the last n migrations (default 1)
+  status          list migrations and whether they are applied
+  to <version>    migrate up or down to the given version, 0 rolls back everything`
+
+// runMigrate implements the migrate subcommand against the configured database
+func runMigrate(cfg config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	dbConn, err := db.Connect(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer db.Close(dbConn, logger)
+
+	switch args[0] {
+	case "up":
+		if err := db.MigrateUp(dbConn); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		if err := db.MigrateDown(dbConn, steps); err != nil {
+			return err
+		}
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("missing target version\n%s", migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := db.MigrateTo(dbConn, version); err != nil {
+			return err
+		}
+	case "status":
+		return printMigrationStatus(dbConn)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+
+	version, err := db.CurrentVersion(dbConn)
+	if err != nil {
+		return err
+	}
+	logger.Info("schema migrated", "version", version, "latest", db.LatestVersion())
+	return nil
+}
+
+func printMigrationStatus(dbConn *gorm.DB) error {
+	statuses, err := db.Status(dbConn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/db"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+const rolesUsage = `usage: roles <grant|revoke> <email> <moderator|support|admin|service>
+
+Grants or revokes a role directly in the database, e.g. to create the first admin.`
+
+// runRoles implements the roles subcommand against the configured database
+func runRoles(cfg config.Config, logger *slog.Logger, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("wrong number of arguments\n%s", rolesUsage)
+	}
+	action, email, role := args[0], args[1], model.Role(args[2])
+	if !role.Valid() || role == model.RoleUser {
+		return fmt.Errorf("invalid role %q\n%s", role, rolesUsage)
+	}
+
+	dbConn, err := db.Connect(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer db.Close(dbConn, logger)
+	if err := db.EnsureMigrated(dbConn); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	repo := repository.NewUserRepository(dbConn)
+	user, err := repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "grant":
+		err = repo.GrantRole(ctx, &model.UserRole{UserID: user.ID, Role: role, GrantedBy: "cli"})
+	case "revoke":
+		err = repo.RevokeRole(ctx, user.ID, role)
+	default:
+		return fmt.Errorf("unknown roles command %q\n%s", action, rolesUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("roles updated", "action", action, "user_id", user.ID, "role", role)
+	return nil
+}
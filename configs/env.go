@@ -3,11 +3,22 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/reputation"
 )
 
 type Config struct {
+	// StorageBackend selects the UserRepository: "database" (default) or "memory"
+	StorageBackend string
+
+	// DBDriver is one of "mysql" (default), "postgres" or "sqlite". For SQLite
+	// DBName is the path of the database file.
+	DBDriver     string
+	DBSSLMode    string
 	DBUser       string
 	DBPassword   string
 	DBName       string
@@ -16,6 +27,52 @@ type Config struct {
 	USERGRPCHost string
 	USERGRPCPort string
 	JWTSecretKey string
+
+	// MailerDriver must be set. Both drivers are for development: "file"
+	// appends messages to MailerFilePath and "log" redacts their bodies.
+	MailerDriver   string
+	MailerFilePath string
+
+	// GeocoderDriver is "none" (default), which stores addresses without
+	// coordinates, or "fixture", which locates the few pincodes in an embedded
+	// table and is meant for tests and local development
+	GeocoderDriver string
+
+	// MaxAddressesPerUser caps how many addresses a user can store; 0 removes the cap
+	MaxAddressesPerUser int
+
+	// RPCTimeout bounds every unary RPC; RPCMethodTimeouts overrides it per
+	// method name, where 0 disables the deadline
+	RPCTimeout        time.Duration
+	RPCMethodTimeouts map[string]time.Duration
+
+	// ShutdownTimeout is how long in-flight RPCs may drain after SIGINT or
+	// SIGTERM before the server is stopped forcefully
+	ShutdownTimeout time.Duration
+
+	// HealthCheckInterval is how often database readiness is re-checked for
+	// the grpc.health.v1 service
+	HealthCheckInterval time.Duration
+
+	// BanSweepInterval is how often temporary bans that have expired are lifted
+	BanSweepInterval time.Duration
+
+	// ReputationTiers names the reputation tiers and their minimum scores,
+	// such as "Bronze=0,Silver=100,Gold=500"
+	ReputationTiers string
+
+	// MetricsAddr is the listen address of the Prometheus /metrics endpoint,
+	// such as ":9090". Metrics are not served when it is empty.
+	MetricsAddr string
+
+	// TracingExporter is "none" (default), "stdout" or "otlp". The OTLP
+	// endpoint comes from the standard OTEL_EXPORTER_OTLP_ENDPOINT variable.
+	TracingExporter    string
+	TracingSampleRatio float64
+
+	// LogLevel is debug, info (default), warn or error; LogFormat is json (default) or text
+	LogLevel  string
+	LogFormat string
 }
 
 func LoadConfig() Config {
@@ -24,6 +81,10 @@ func LoadConfig() Config {
 	}
 
 	return Config{
+		StorageBackend: getEnv("STORAGE_BACKEND", "database"),
+
+		DBDriver:     getEnv("DBDRIVER", "mysql"),
+		DBSSLMode:    getEnv("DBSSLMODE", "disable"),
 		DBUser:       os.Getenv("DBUSER"),
 		DBPassword:   os.Getenv("DBPASSWORD"),
 		DBName:       os.Getenv("DBNAME"),
@@ -32,5 +93,105 @@ func LoadConfig() Config {
 		USERGRPCHost: os.Getenv("USERGRPCHOST"),
 		USERGRPCPort: os.Getenv("USERGRPCPORT"),
 		JWTSecretKey: os.Getenv("JWTSECRET"),
+
+		MailerDriver:   os.Getenv("MAILER"),
+		MailerFilePath: getEnv("MAILER_FILE", "mail.log"),
+
+		GeocoderDriver: getEnv("GEOCODER", "none"),
+
+		MaxAddressesPerUser: getIntEnv("MAX_ADDRESSES_PER_USER", 20),
+
+		RPCTimeout:        getDurationEnv("RPC_TIMEOUT", 10*time.Second),
+		RPCMethodTimeouts: getDurationMapEnv("RPC_METHOD_TIMEOUTS"),
+
+		ShutdownTimeout:     getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+		HealthCheckInterval: getDurationEnv("HEALTH_CHECK_INTERVAL", 10*time.Second),
+		BanSweepInterval:    getDurationEnv("BAN_SWEEP_INTERVAL", time.Minute),
+
+		ReputationTiers: getEnv("REPUTATION_TIERS", reputation.DefaultTiers),
+
+		MetricsAddr: os.Getenv("METRICS_ADDR"),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingSampleRatio: getFloatEnv("TRACING_SAMPLE_RATIO", 1),
+
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+	}
+}
+
+// getEnv returns the value of the environment variable or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getDurationEnv parses a duration such as "5s" from the environment
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getIntEnv parses a whole number such as "20" from the environment
+func getIntEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid number %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getFloatEnv parses a number such as "0.25" from the environment
+func getFloatEnv(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid number %q for %s, using %v", value, key, fallback)
+		return fallback
+	}
+	return f
+}
+
+// getDurationMapEnv parses a list like "ListUsers=5s,ExportUsers=0" from the environment
+func getDurationMapEnv(key string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			log.Printf("Ignoring malformed %s entry %q", key, entry)
+			continue
+		}
+		if strings.TrimSpace(value) == "0" {
+			durations[strings.TrimSpace(name)] = 0
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			log.Printf("Ignoring invalid duration in %s entry %q", key, entry)
+			continue
+		}
+		durations[strings.TrimSpace(name)] = d
 	}
+	return durations
 }
@@ -1,29 +1,35 @@
 package db
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
 	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
-	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/logging"
 )
 
-// Connect establishes a connection to the SQLite database using GORM and configures connection pool settings.
+// SlowQueryThreshold is the duration above which queries are logged as warnings
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// Connect establishes a connection to the database selected by cfg.DBDriver using GORM
+// and configures connection pool settings. The schema is managed separately by
+// the versioned migrations in this package. Queries are logged through logger.
 // Returns a GORM database instance or an error if the connection fails.
-func Connect(cfg config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
-	)
+func Connect(cfg config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError maps driver specific errors such as duplicate keys to gorm errors
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		Logger:         logging.NewGormLogger(logger, SlowQueryThreshold),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -39,32 +45,42 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 	sqlDB.SetConnMaxLifetime(5 * time.Minute)
 	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
 
-	// Auto-migrate database schema for all models
-	if err := db.AutoMigrate(&model.User{},&model.UserAddress{}); err != nil {
-		return nil, fmt.Errorf("auto-migration failed: %w", err)
+	// SQLite allows a single writer, so serialise access through one connection
+	if db.Dialector.Name() == DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
 	}
 
-	log.Println("Connected to MySQL database and schema migrated")
+	logger.Info("connected to database", "driver", db.Dialector.Name())
 	return db, nil
 }
 
-// Close terminates the MySQL database connection safely.
-func Close(db *gorm.DB) {
+// Ping verifies the database is reachable within the deadline of ctx.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve SQL DB instance: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close terminates the database connection safely.
+func Close(db *gorm.DB, logger *slog.Logger) {
 	if db == nil {
-		log.Println("No active database connection to close.")
+		logger.Warn("no active database connection to close")
 		return
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Printf("Failed to retrieve SQL DB instance for closure: %v", err)
+		logger.Error("failed to retrieve SQL DB instance for closure", "error", err)
 		return
 	}
 
 	if err := sqlDB.Close(); err != nil {
-		log.Printf("Failed to close database connection: %v", err)
+		logger.Error("failed to close database connection", "error", err)
 		return
 	}
 
-	log.Println("Database connection closed")
+	logger.Info("database connection closed")
 }
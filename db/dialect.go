@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Dialector builds the GORM dialector and DSN for cfg.DBDriver
+func Dialector(cfg config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case DriverMySQL, "":
+		// clientFoundRows makes RowsAffected count matched rows like the other
+		// dialects do, instead of only rows whose values changed
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&clientFoundRows=true",
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBHost,
+			cfg.DBPort,
+			cfg.DBName,
+		)
+		return mysql.Open(dsn), nil
+
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			cfg.DBHost,
+			cfg.DBPort,
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBName,
+			cfg.DBSSLMode,
+		)
+		return postgres.Open(dsn), nil
+
+	case DriverSQLite:
+		// DBName is the database file path
+		dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", cfg.DBName)
+		return sqlite.Open(dsn), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.DBDriver)
+	}
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaOutdated is returned by EnsureMigrated when migrations are pending
+var ErrSchemaOutdated = errors.New("database schema is not up to date, run the migrate command")
+
+// Migration is one versioned schema change. Down must undo exactly what Up did.
+// Each step runs in a transaction, although MySQL commits DDL statements implicitly.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration in the schema_migrations table
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus reports whether a known migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LatestVersion is the version the schema reaches once every migration is applied
+func LatestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// CurrentVersion returns the highest applied migration version, 0 for an empty schema
+func CurrentVersion(db *gorm.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Status lists every known migration with its applied state
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		record, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{Migration: m, Applied: ok, AppliedAt: record.AppliedAt})
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies all pending migrations
+func MigrateUp(db *gorm.DB) error {
+	return MigrateTo(db, LatestVersion())
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations
+func MigrateDown(db *gorm.DB, steps int) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	target := 0
+	if steps < len(versions) {
+		target = versions[len(versions)-steps-1]
+	}
+	return MigrateTo(db, target)
+}
+
+// MigrateTo applies or rolls back migrations until the schema is at version
+func MigrateTo(db *gorm.DB, version int) error {
+	if version != 0 && findMigration(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to prepare schema_migrations table: %w", err)
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok || m.Version > version {
+			continue
+		}
+		if err := runMigration(db, m, true); err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok || m.Version <= version {
+			continue
+		}
+		if err := runMigration(db, m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureMigrated returns ErrSchemaOutdated unless every migration has been applied
+func EnsureMigrated(db *gorm.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			return fmt.Errorf("%w: migration %d (%s) is pending", ErrSchemaOutdated, m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+func runMigration(db *gorm.DB, m Migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if up {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{Version: m.Version}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d (%s) %s failed: %w", m.Version, m.Name, direction, err)
+	}
+
+	// Migrations run before the service is wired up, so they use the default logger
+	slog.InfoContext(db.Statement.Context, "applied migration", "direction", direction, "version", m.Version, "name", m.Name)
+	return nil
+}
+
+// appliedMigrations reads the schema_migrations table. A missing table means
+// nothing has been applied; it is only created by migrations that modify the schema.
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	applied := make(map[int]SchemaMigration)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func findMigration(version int) *Migration {
+	i := sort.Search(len(migrations), func(i int) bool { return migrations[i].Version >= version })
+	if i < len(migrations) && migrations[i].Version == version {
+		return &migrations[i]
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestMigrateUpDownRoundTrip(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := EnsureMigrated(conn); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("EnsureMigrated on empty schema = %v, want ErrSchemaOutdated", err)
+	}
+
+	if err := MigrateUp(conn); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if err := EnsureMigrated(conn); err != nil {
+		t.Fatalf("EnsureMigrated after MigrateUp: %v", err)
+	}
+	if version, _ := CurrentVersion(conn); version != LatestVersion() {
+		t.Fatalf("CurrentVersion = %d, want %d", version, LatestVersion())
+	}
+
+	// Applying again is a no-op
+	if err := MigrateUp(conn); err != nil {
+		t.Fatalf("second MigrateUp: %v", err)
+	}
+
+	if err := MigrateDown(conn, len(migrations)); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if version, _ := CurrentVersion(conn); version != 0 {
+		t.Fatalf("CurrentVersion after full rollback = %d, want 0", version)
+	}
+	if conn.Migrator().HasTable("users") {
+		t.Fatal("users table still exists after full rollback")
+	}
+
+	statuses, err := Status(conn)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d still applied after rollback", s.Version)
+		}
+	}
+
+	// Every migration must be reversible and re-applicable
+	if err := MigrateUp(conn); err != nil {
+		t.Fatalf("MigrateUp after rollback: %v", err)
+	}
+}
+
+func TestMigrateToUnknownVersion(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := MigrateTo(conn, LatestVersion()+1); err == nil {
+		t.Fatal("MigrateTo unknown version succeeded")
+	}
+}
+
+func TestMigrateBackfillsBansAndReputation(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := MigrateTo(conn, 2); err != nil {
+		t.Fatalf("MigrateTo(2): %v", err)
+	}
+	users := []userV1{
+		{ID: "usr_banned", Email: "banned@example.com", IsBanned: true, Reputation: -5},
+		{ID: "usr_plain", Email: "plain@example.com"},
+	}
+	if err := conn.Create(&users).Error; err != nil {
+		t.Fatalf("insert users: %v", err)
+	}
+
+	if err := MigrateTo(conn, 4); err != nil {
+		t.Fatalf("MigrateTo(4): %v", err)
+	}
+	var bans []userBanV3
+	if err := conn.Find(&bans).Error; err != nil {
+		t.Fatalf("read user_bans: %v", err)
+	}
+	if len(bans) != 1 || bans[0].UserID != "usr_banned" || bans[0].ExpiresAt != nil || bans[0].LiftedAt != nil {
+		t.Errorf("user_bans after migration = %+v, want one active permanent ban of usr_banned", bans)
+	}
+	var events []reputationEventV4
+	if err := conn.Find(&events).Error; err != nil {
+		t.Fatalf("read reputation_events: %v", err)
+	}
+	if len(events) != 1 || events[0].UserID != "usr_banned" || events[0].Delta != -5 {
+		t.Errorf("reputation_events after migration = %+v, want an opening balance of -5 for usr_banned", events)
+	}
+}
+
+func TestMigrateAddressDefaults(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := MigrateTo(conn, 5); err != nil {
+		t.Fatalf("MigrateTo(5): %v", err)
+	}
+	addresses := []userAddressV1{
+		{ID: "addr_2", UserID: "usr_1"},
+		{ID: "addr_1", UserID: "usr_1"},
+		{ID: "addr_3", UserID: "usr_2"},
+	}
+	if err := conn.Create(&addresses).Error; err != nil {
+		t.Fatalf("insert addresses: %v", err)
+	}
+
+	if err := MigrateTo(conn, 6); err != nil {
+		t.Fatalf("MigrateTo(6): %v", err)
+	}
+	var defaults []string
+	if err := conn.Table("user_addresses").Where("is_default = ?", true).Order("id").Pluck("id", &defaults).Error; err != nil {
+		t.Fatalf("read defaults: %v", err)
+	}
+	if !reflect.DeepEqual(defaults, []string{"addr_1", "addr_3"}) {
+		t.Errorf("default addresses = %v, want [addr_1 addr_3]", defaults)
+	}
+	var unlabelled int64
+	conn.Table("user_addresses").Where("label <> ? OR created_at IS NULL", "other").Count(&unlabelled)
+	if unlabelled != 0 {
+		t.Errorf("%d addresses not backfilled", unlabelled)
+	}
+}
+
+func TestMigrateAddressOwner(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := MigrateTo(conn, 7); err != nil {
+		t.Fatalf("MigrateTo(7): %v", err)
+	}
+	if err := conn.Create(&userV1{ID: "usr_1", Email: "alice@example.com"}).Error; err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	addresses := []userAddressV1{
+		{ID: "addr_1", UserID: "usr_1"},
+		{ID: "addr_2", UserID: "usr_deleted"},
+	}
+	if err := conn.Create(&addresses).Error; err != nil {
+		t.Fatalf("insert addresses: %v", err)
+	}
+
+	if err := MigrateTo(conn, 8); err != nil {
+		t.Fatalf("MigrateTo(8): %v", err)
+	}
+	var ids []string
+	if err := conn.Table("user_addresses").Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("read addresses: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"addr_1"}) {
+		t.Errorf("addresses after migration = %v, want [addr_1]", ids)
+	}
+	if !conn.Migrator().HasIndex(&userAddressOwnerV8{}, "idx_user_addresses_user_id") {
+		t.Error("idx_user_addresses_user_id was not created")
+	}
+	if !conn.Migrator().HasConstraint(&userAddressOwnerV8{}, "User") {
+		t.Error("foreign key to users was not created")
+	}
+	if err := conn.Create(&userAddressV1{ID: "addr_3", UserID: "usr_missing"}).Error; err == nil {
+		t.Error("address of a missing user was accepted")
+	}
+	if err := conn.Delete(&userV1{ID: "usr_1"}).Error; err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	var remaining int64
+	conn.Table("user_addresses").Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("%d addresses left after deleting their user", remaining)
+	}
+
+	if err := MigrateTo(conn, 7); err != nil {
+		t.Fatalf("MigrateTo(7) rollback: %v", err)
+	}
+	if conn.Migrator().HasConstraint(&userAddressOwnerV8{}, "User") {
+		t.Error("constraint still exists after rollback")
+	}
+}
+
+func TestMigrateDropUsersColumnKeepsAddresses(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := MigrateTo(conn, 9); err != nil {
+		t.Fatalf("MigrateTo(9): %v", err)
+	}
+	if err := conn.Create(&userV1{ID: "usr_1", Email: "alice@example.com"}).Error; err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := conn.Create(&userAddressV1{ID: "addr_1", UserID: "usr_1"}).Error; err != nil {
+		t.Fatalf("insert address: %v", err)
+	}
+
+	// Rolling back drops users.token_version while user_addresses references users
+	if err := MigrateTo(conn, 8); err != nil {
+		t.Fatalf("MigrateTo(8): %v", err)
+	}
+	if conn.Migrator().HasColumn(&userTokenVersionV9{}, "TokenVersion") {
+		t.Error("token_version still exists after rollback")
+	}
+	var addresses int64
+	conn.Table("user_addresses").Count(&addresses)
+	if addresses != 1 {
+		t.Errorf("%d addresses after dropping a users column, want 1", addresses)
+	}
+	for _, index := range []string{"idx_users_email", "idx_users_created_at", "idx_users_leaderboard"} {
+		if !conn.Migrator().HasIndex("users", index) {
+			t.Errorf("%s lost when dropping a users column", index)
+		}
+	}
+}
+
+// userBaseline is the users table as AutoMigrate created it before versioned
+// migrations, without created_at
+type userBaseline struct {
+	ID           string `gorm:"primaryKey;type:varchar(255)"`
+	Email        string `gorm:"type:varchar(255);uniqueIndex"`
+	PasswordHash string `gorm:"type:varchar(255)"`
+	IsVerified   bool
+}
+
+func (userBaseline) TableName() string { return "users" }
+
+func TestMigrateBackfillsUserCreatedAt(t *testing.T) {
+	conn, err := Connect(config.Config{DBDriver: DriverSQLite, DBName: filepath.Join(t.TempDir(), "migrate.db")}, testLogger)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { Close(conn, testLogger) })
+
+	if err := conn.AutoMigrate(&userBaseline{}); err != nil {
+		t.Fatalf("create baseline users: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		user := &userBaseline{ID: fmt.Sprintf("usr_%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+		if err := conn.Create(user).Error; err != nil {
+			t.Fatalf("insert %s: %v", user.ID, err)
+		}
+	}
+
+	if err := MigrateUp(conn); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	var missing int64
+	conn.Table("users").Where("created_at IS NULL").Count(&missing)
+	if missing != 0 {
+		t.Errorf("%d users without created_at after MigrateUp", missing)
+	}
+
+	repo := repository.NewUserRepository(conn)
+	seen := map[string]bool{}
+	options := repository.UserListOptions{Limit: 2}
+	for pages := 0; pages < 5; pages++ {
+		page, err := repo.ListUsers(context.Background(), options)
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		for _, user := range page.Users {
+			seen[user.ID] = true
+		}
+		if page.NextAfterID == "" {
+			break
+		}
+		options.AfterID = page.NextAfterID
+	}
+	if len(seen) != 5 {
+		t.Errorf("paging listed %d of 5 users created before migrations", len(seen))
+	}
+}
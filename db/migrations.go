@@ -0,0 +1,430 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations is the ordered history of schema changes. Applied migrations must
+// never be edited; add a new one instead. Each migration declares its own
+// snapshot of the tables it touches so later model changes cannot alter it.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_users_addresses_sessions_password_resets",
+		// AutoMigrate rather than CreateTable so databases created before
+		// versioned migrations are adopted as-is
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userV1{}, &userAddressV1{}, &sessionV1{}, &passwordResetTokenV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordResetTokenV1{}, &sessionV1{}, &userAddressV1{}, &userV1{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create_user_roles",
+		// Users can hold several roles. "user" is implicit and never stored.
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&userRoleV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userRoleV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_user_bans",
+		// Users banned before bans had a history get a permanent ban with no
+		// reason, so every banned user has an active ban to lift
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&userBanV3{}); err != nil {
+				return err
+			}
+			var bannedIDs []string
+			if err := tx.Model(&userV1{}).Where("is_banned = ?", true).Pluck("id", &bannedIDs).Error; err != nil {
+				return err
+			}
+			now := time.Now()
+			for _, userID := range bannedIDs {
+				ban := &userBanV3{ID: "ban_" + uuid.NewString(), UserID: userID, CreatedAt: now}
+				if err := tx.Create(ban).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userBanV3{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_reputation_events",
+		// Existing scores are carried over as an opening balance so the
+		// cached reputation keeps matching the ledger sum
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&reputationEventV4{}); err != nil {
+				return err
+			}
+			var users []userV1
+			if err := tx.Select("id", "reputation").Where("reputation <> 0").Find(&users).Error; err != nil {
+				return err
+			}
+			now := time.Now()
+			for _, user := range users {
+				event := &reputationEventV4{
+					ID:             "rep_" + uuid.NewString(),
+					UserID:         user.ID,
+					Delta:          user.Reputation,
+					Reason:         "opening balance",
+					SourceService:  "user",
+					IdempotencyKey: "opening-balance:" + user.ID,
+					CreatedAt:      now,
+				}
+				if err := tx.Create(event).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&reputationEventV4{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_users_leaderboard_index",
+		// Leaderboards rank unbanned users by reputation, so is_banned leads
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateIndex(&userLeaderboardV5{}, "idx_users_leaderboard")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&userLeaderboardV5{}, "idx_users_leaderboard")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_user_addresses_details",
+		// Existing addresses are labelled other and each user's oldest one,
+		// by its time-based ID, becomes the default
+		Up: func(tx *gorm.DB) error {
+			for _, column := range userAddressDetailsV7Columns {
+				if err := tx.Migrator().AddColumn(&userAddressDetailsV6{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&userAddressDetailsV6{}).Where("created_at IS NULL").Update("created_at", time.Now()).Error; err != nil {
+				return err
+			}
+			// The derived table lets MySQL read the table it updates
+			return tx.Exec(`UPDATE user_addresses SET is_default = ? WHERE id IN
+				(SELECT id FROM (SELECT MIN(id) AS id FROM user_addresses GROUP BY user_id) AS oldest)`, true).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(userAddressDetailsV7Columns) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropColumn(&userAddressDetailsV6{}, userAddressDetailsV7Columns[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_user_addresses_coordinates",
+		// Existing addresses stay without coordinates until they are edited
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&userAddressCoordinatesV7{}, "Latitude"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&userAddressCoordinatesV7{}, "Longitude")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&userAddressCoordinatesV7{}, "Longitude"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&userAddressCoordinatesV7{}, "Latitude")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_user_addresses_user_fk",
+		// Addresses of users that no longer exist are unreachable and would
+		// fail the constraint, so they are deleted first. The constraint goes
+		// on before the index because SQLite adds it by rebuilding the table.
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`DELETE FROM user_addresses WHERE user_id NOT IN (SELECT id FROM users)`).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateConstraint(&userAddressOwnerV8{}, "User"); err != nil {
+				return err
+			}
+			if tx.Migrator().HasIndex(&userAddressOwnerV8{}, "idx_user_addresses_user_id") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&userAddressOwnerV8{}, "idx_user_addresses_user_id")
+		},
+		// MySQL refuses to drop an index a constraint relies on, so the
+		// constraint is dropped first
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropConstraint(&userAddressOwnerV8{}, "User"); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasIndex(&userAddressOwnerV8{}, "idx_user_addresses_user_id") {
+				return nil
+			}
+			return tx.Migrator().DropIndex(&userAddressOwnerV8{}, "idx_user_addresses_user_id")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add_users_token_version",
+		// Tokens issued before have no version, which reads as 0
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&userTokenVersionV9{}, "TokenVersion")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropUsersColumn(tx, &userTokenVersionV9{}, "TokenVersion")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_users_verification_sends",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&userVerificationSendsV10{}, "VerificationSentAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&userVerificationSendsV10{}, "VerificationSends")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropUsersColumn(tx, &userVerificationSendsV10{}, "VerificationSends"); err != nil {
+				return err
+			}
+			return dropUsersColumn(tx, &userVerificationSendsV10{}, "VerificationSentAt")
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_users_password_reset_sends",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&userPasswordResetSendsV11{}, "PasswordResetSentAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&userPasswordResetSendsV11{}, "PasswordResetSends")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropUsersColumn(tx, &userPasswordResetSendsV11{}, "PasswordResetSends"); err != nil {
+				return err
+			}
+			return dropUsersColumn(tx, &userPasswordResetSendsV11{}, "PasswordResetSentAt")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "lowercase_users_email",
+		// Emails are now stored lower-cased. On PostgreSQL and SQLite this
+		// fails if two accounts differ only in case, which must be merged by
+		// hand first; MySQL never allowed them.
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE users SET email = LOWER(email), pending_email = LOWER(pending_email)").Error
+		},
+		// The original case is not kept
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	},
+	{
+		Version: 13,
+		Name:    "backfill_users_created_at",
+		// Users from before migration 1 have no created_at, which drops them
+		// from pages ordered by it. Their signup time is unknown, so they are
+		// dated to the migration. SQLite can only add NOT NULL by rebuilding
+		// the table, which deletes every address, so there it stays nullable.
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE users SET created_at = ? WHERE created_at IS NULL", time.Now()).Error; err != nil {
+				return err
+			}
+			if tx.Dialector.Name() == DriverSQLite {
+				return nil
+			}
+			return tx.Migrator().AlterColumn(&userCreatedAtV13{}, "CreatedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == DriverSQLite {
+				return nil
+			}
+			return tx.Migrator().AlterColumn(&userV1{}, "CreatedAt")
+		},
+	},
+}
+
+// dropUsersColumn drops a column of the users table. GORM drops SQLite columns
+// by rebuilding the table, which loses its indexes and, once user_addresses
+// references users, deletes every address through the cascade. SQLite's own
+// DROP COLUMN alters the table in place.
+func dropUsersColumn(tx *gorm.DB, snapshot interface{}, column string) error {
+	if tx.Dialector.Name() != DriverSQLite {
+		return tx.Migrator().DropColumn(snapshot, column)
+	}
+	name := tx.NamingStrategy.ColumnName("users", column)
+	return tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: "users"}, clause.Column{Name: name}).Error
+}
+
+type userV1 struct {
+	ID           string `gorm:"primaryKey;type:varchar(255)"`
+	Email        string `gorm:"type:varchar(255);uniqueIndex:idx_users_email"`
+	PasswordHash string `gorm:"type:varchar(255)"`
+	Name         string `gorm:"type:varchar(255)"`
+	PhoneNumber  uint64
+	Reputation   int32
+	IsBanned     bool
+	IsVerified   bool
+	CreatedAt    time.Time `gorm:"index:idx_users_created_at"`
+
+	VerificationCode      string `gorm:"type:varchar(255)"`
+	VerificationExpiresAt *time.Time
+	VerificationAttempts  int
+
+	PendingEmail         string `gorm:"type:varchar(255)"`
+	EmailChangeCode      string `gorm:"type:varchar(255)"`
+	EmailChangeExpiresAt *time.Time
+	EmailChangeAttempts  int
+}
+
+func (userV1) TableName() string { return "users" }
+
+type userAddressV1 struct {
+	ID         string `gorm:"primaryKey;type:varchar(255)"`
+	UserID     string `gorm:"type:varchar(255)"`
+	StreetName string `gorm:"type:varchar(255)"`
+	Locality   string `gorm:"type:varchar(255)"`
+	State      string `gorm:"type:varchar(255)"`
+	Pincode    string `gorm:"type:varchar(6)"`
+}
+
+func (userAddressV1) TableName() string { return "user_addresses" }
+
+type sessionV1 struct {
+	ID               string `gorm:"primaryKey;type:varchar(255)"`
+	UserID           string `gorm:"type:varchar(255);index:idx_sessions_user_id"`
+	FamilyID         string `gorm:"type:varchar(255);index:idx_sessions_family_id"`
+	RefreshTokenHash string `gorm:"type:varchar(64);uniqueIndex:idx_sessions_refresh_token_hash"`
+	DeviceInfo       string `gorm:"type:varchar(255)"`
+	ExpiresAt        time.Time
+	RotatedAt        *time.Time
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+func (sessionV1) TableName() string { return "sessions" }
+
+type passwordResetTokenV1 struct {
+	ID        string `gorm:"primaryKey;type:varchar(255)"`
+	UserID    string `gorm:"type:varchar(255);index:idx_password_reset_tokens_user_id"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex:idx_password_reset_tokens_token_hash"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (passwordResetTokenV1) TableName() string { return "password_reset_tokens" }
+
+type userRoleV2 struct {
+	UserID    string `gorm:"primaryKey;type:varchar(255)"`
+	Role      string `gorm:"primaryKey;type:varchar(32)"`
+	GrantedBy string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+}
+
+func (userRoleV2) TableName() string { return "user_roles" }
+
+type userBanV3 struct {
+	ID        string `gorm:"primaryKey;type:varchar(255)"`
+	UserID    string `gorm:"type:varchar(255);index:idx_user_bans_user_id"`
+	Reason    string `gorm:"type:varchar(1024)"`
+	BannedBy  string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	LiftedAt  *time.Time
+	LiftedBy  string `gorm:"type:varchar(255)"`
+}
+
+func (userBanV3) TableName() string { return "user_bans" }
+
+type reputationEventV4 struct {
+	ID             string `gorm:"primaryKey;type:varchar(255)"`
+	UserID         string `gorm:"type:varchar(255);index:idx_reputation_events_user_id"`
+	Delta          int32
+	Reason         string `gorm:"type:varchar(255)"`
+	SourceService  string `gorm:"type:varchar(64);uniqueIndex:idx_reputation_events_idempotency"`
+	ReferenceID    string `gorm:"type:varchar(255)"`
+	IdempotencyKey string `gorm:"type:varchar(255);uniqueIndex:idx_reputation_events_idempotency"`
+	CreatedAt      time.Time
+}
+
+func (reputationEventV4) TableName() string { return "reputation_events" }
+
+type userLeaderboardV5 struct {
+	IsBanned   bool  `gorm:"index:idx_users_leaderboard,priority:1"`
+	Reputation int32 `gorm:"index:idx_users_leaderboard,priority:2"`
+}
+
+func (userLeaderboardV5) TableName() string { return "users" }
+
+type userAddressDetailsV6 struct {
+	Label                string `gorm:"type:varchar(16);not null;default:other"`
+	IsDefault            bool   `gorm:"not null;default:false"`
+	Landmark             string `gorm:"type:varchar(255)"`
+	FlatNumber           string `gorm:"type:varchar(64)"`
+	DeliveryInstructions string `gorm:"type:varchar(500)"`
+	CreatedAt            time.Time
+}
+
+func (userAddressDetailsV6) TableName() string { return "user_addresses" }
+
+var userAddressDetailsV7Columns = []string{"Label", "IsDefault", "Landmark", "FlatNumber", "DeliveryInstructions", "CreatedAt"}
+
+type userAddressCoordinatesV7 struct {
+	Latitude  *float64 `gorm:"type:double precision"`
+	Longitude *float64 `gorm:"type:double precision"`
+}
+
+func (userAddressCoordinatesV7) TableName() string { return "user_addresses" }
+
+type userAddressOwnerV8 struct {
+	ID     string `gorm:"primaryKey;type:varchar(255)"`
+	UserID string `gorm:"type:varchar(255);index:idx_user_addresses_user_id"`
+	User   userV1 `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (userAddressOwnerV8) TableName() string { return "user_addresses" }
+
+type userTokenVersionV9 struct {
+	TokenVersion int `gorm:"not null;default:0"`
+}
+
+func (userTokenVersionV9) TableName() string { return "users" }
+
+type userVerificationSendsV10 struct {
+	VerificationSentAt *time.Time
+	VerificationSends  int `gorm:"not null;default:0"`
+}
+
+func (userVerificationSendsV10) TableName() string { return "users" }
+
+type userPasswordResetSendsV11 struct {
+	PasswordResetSentAt *time.Time
+	PasswordResetSends  int `gorm:"not null;default:0"`
+}
+
+func (userPasswordResetSendsV11) TableName() string { return "users" }
+
+type userCreatedAtV13 struct {
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (userCreatedAtV13) TableName() string { return "users" }
@@ -0,0 +1,57 @@
+package geocoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+//go:embed fixtures/pincodes.csv
+var pincodeFixture []byte
+
+// FixtureGeocoder locates addresses offline by the centre of their pincode,
+// using a small embedded table. It suits tests and local development.
+type FixtureGeocoder struct {
+	pincodes map[string]Coordinates
+}
+
+// NewFixtureGeocoder loads the embedded pincode table
+func NewFixtureGeocoder() (*FixtureGeocoder, error) {
+	pincodes := make(map[string]Coordinates)
+	scanner := bufio.NewScanner(bytes.NewReader(pincodeFixture))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("pincode fixture line %d: want pincode,latitude,longitude", line)
+		}
+		lat, latErr := strconv.ParseFloat(fields[1], 64)
+		lon, lonErr := strconv.ParseFloat(fields[2], 64)
+		coords := Coordinates{Latitude: lat, Longitude: lon}
+		if latErr != nil || lonErr != nil || !coords.Valid() {
+			return nil, fmt.Errorf("pincode fixture line %d: invalid coordinates", line)
+		}
+		pincodes[fields[0]] = coords
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pincode fixture: %w", err)
+	}
+	return &FixtureGeocoder{pincodes: pincodes}, nil
+}
+
+func (g *FixtureGeocoder) Geocode(ctx context.Context, address *model.UserAddress) (Coordinates, error) {
+	coords, ok := g.pincodes[strings.TrimSpace(address.Pincode)]
+	if !ok {
+		return Coordinates{}, fmt.Errorf("%w: unknown pincode %q", ErrNotFound, address.Pincode)
+	}
+	return coords, nil
+}
@@ -0,0 +1,68 @@
+// Package geocoder resolves addresses to coordinates.
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// ErrNotFound is returned when an address cannot be located
+var ErrNotFound = errors.New("address could not be geocoded")
+
+// Coordinates is a point in decimal degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Valid reports whether c lies within the latitude and longitude ranges
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
+
+// Geocoder locates addresses. Implementations must be safe for concurrent use.
+type Geocoder interface {
+	Geocode(ctx context.Context, address *model.UserAddress) (Coordinates, error)
+}
+
+// New returns the Geocoder selected by cfg.GeocoderDriver
+func New(cfg config.Config) (Geocoder, error) {
+	switch cfg.GeocoderDriver {
+	case "", "none":
+		return Disabled{}, nil
+	case "fixture":
+		return NewFixtureGeocoder()
+	default:
+		return nil, fmt.Errorf("unknown geocoder driver %q", cfg.GeocoderDriver)
+	}
+}
+
+// Disabled locates no address, so addresses are stored without coordinates
+type Disabled struct{}
+
+func (Disabled) Geocode(ctx context.Context, address *model.UserAddress) (Coordinates, error) {
+	return Coordinates{}, ErrNotFound
+}
+
+// earthRadiusMeters is the mean radius of the Earth
+const earthRadiusMeters = 6371008.8
+
+// Distance returns the great-circle distance between a and b in meters
+// using the haversine formula
+func Distance(a, b Coordinates) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
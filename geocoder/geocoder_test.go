@@ -0,0 +1,72 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b Coordinates
+		want float64
+	}{
+		{Coordinates{0, 0}, Coordinates{0, 0}, 0},
+		// One degree along the equator
+		{Coordinates{0, 0}, Coordinates{0, 1}, 111195},
+		// Kochi to Bengaluru
+		{Coordinates{9.9700, 76.2900}, Coordinates{12.9716, 77.5946}, 362500},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); math.Abs(got-tt.want) > 1000 {
+			t.Errorf("Distance(%v, %v) = %.0f, want about %.0f", tt.a, tt.b, got, tt.want)
+		}
+		if got, back := Distance(tt.a, tt.b), Distance(tt.b, tt.a); math.Abs(got-back) > 1e-6 {
+			t.Errorf("Distance is not symmetric: %f and %f", got, back)
+		}
+	}
+}
+
+func TestFixtureGeocoder(t *testing.T) {
+	g, err := NewFixtureGeocoder()
+	if err != nil {
+		t.Fatalf("NewFixtureGeocoder: %v", err)
+	}
+	ctx := context.Background()
+
+	coords, err := g.Geocode(ctx, &model.UserAddress{Pincode: "682016"})
+	if err != nil || coords != (Coordinates{9.9700, 76.2900}) {
+		t.Errorf("Geocode(682016) = %v, %v", coords, err)
+	}
+	if _, err := g.Geocode(ctx, &model.UserAddress{Pincode: "999999"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Geocode of unknown pincode: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, driver := range []string{"", "none"} {
+		geo, err := New(config.Config{GeocoderDriver: driver})
+		if err != nil {
+			t.Fatalf("New(%q): %v", driver, err)
+		}
+		if _, ok := geo.(Disabled); !ok {
+			t.Errorf("New(%q) = %T, want Disabled", driver, geo)
+		}
+	}
+
+	geo, err := New(config.Config{GeocoderDriver: "fixture"})
+	if err != nil {
+		t.Fatalf("New(fixture): %v", err)
+	}
+	if _, ok := geo.(*FixtureGeocoder); !ok {
+		t.Errorf("New(fixture) = %T, want *FixtureGeocoder", geo)
+	}
+
+	if _, err := New(config.Config{GeocoderDriver: "maps"}); err == nil {
+		t.Error("New accepted an unknown driver")
+	}
+}
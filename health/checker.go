@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Probe reports whether a dependency of the service is ready
+type Probe func(ctx context.Context) error
+
+// Checker runs a readiness probe periodically and publishes the result for the
+// given services on a grpc.health.v1 server. The empty service name reports
+// the overall health of the process.
+type Checker struct {
+	server   *health.Server
+	probe    Probe
+	interval time.Duration
+	services []string
+	logger   *slog.Logger
+}
+
+// NewChecker creates a checker that starts in NOT_SERVING until the first
+// successful probe
+func NewChecker(server *health.Server, probe Probe, interval time.Duration, logger *slog.Logger, services ...string) *Checker {
+	c := &Checker{
+		server:   server,
+		probe:    probe,
+		interval: interval,
+		services: append([]string{""}, services...),
+		logger:   logger,
+	}
+	c.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Run probes immediately and then on every interval until ctx is cancelled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	current := healthpb.HealthCheckResponse_NOT_SERVING
+	for {
+		next := c.check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if next != current {
+			c.logger.Info("health status changed", "from", current.String(), "to", next.String())
+			current = next
+			c.setStatus(current)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Checker) check(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	// A probe must not take longer than the interval between probes
+	probeCtx, cancel := context.WithTimeout(ctx, c.interval)
+	defer cancel()
+
+	if err := c.probe(probeCtx); err != nil {
+		c.logger.Warn("readiness probe failed", "error", err)
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+func (c *Checker) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestCheckerFollowsProbe(t *testing.T) {
+	server := health.NewServer()
+	var failing atomic.Bool
+	probe := func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("database unreachable")
+		}
+		return nil
+	}
+
+	checker := NewChecker(server, probe, 10*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)), "user.UserService")
+	waitForStatus(t, server, "user.UserService", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, server, "", healthpb.HealthCheckResponse_SERVING)
+	waitForStatus(t, server, "user.UserService", healthpb.HealthCheckResponse_SERVING)
+
+	failing.Store(true)
+	waitForStatus(t, server, "user.UserService", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func waitForStatus(t *testing.T, server *health.Server, service string, want healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err == nil && resp.Status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status of %q = %v (err %v), want %v", service, resp.GetStatus(), err, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// Authenticator resolves a bearer token to the caller it was issued to
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*auth.Principal, error)
+}
+
+// AuthUnaryInterceptor requires a valid "authorization: Bearer <token>"
+// metadata entry on every RPC except those in publicMethods, keyed by full
+// method name, and stores the caller in the context for the handlers.
+// Handlers decide whether the caller may act on the requested user.
+func AuthUnaryInterceptor(authenticator Authenticator, publicMethods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, authenticator)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is the streaming counterpart of AuthUnaryInterceptor
+func AuthStreamInterceptor(authenticator Authenticator, publicMethods map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), authenticator)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, authenticator Authenticator) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: missing bearer token", model.ErrInvalidToken)
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+		return "", fmt.Errorf("%w: malformed authorization header", model.ErrInvalidToken)
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,152 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+const (
+	publicMethod    = "/user.UserService/UserLogin"
+	protectedMethod = "/user.UserService/GetProfile"
+)
+
+var testPublicMethods = map[string]bool{publicMethod: true}
+
+// fakeAuthenticator accepts only the tokens it knows. A nil principal stands
+// for a token that was revoked.
+type fakeAuthenticator map[string]*auth.Principal
+
+func (a fakeAuthenticator) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	principal, ok := a[token]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown token", model.ErrInvalidToken)
+	}
+	if principal == nil {
+		return nil, fmt.Errorf("%w: session revoked", model.ErrInvalidToken)
+	}
+	return principal, nil
+}
+
+var testAuthenticator = fakeAuthenticator{"good-token": {UserID: "usr_1"}, "revoked-token": nil}
+
+// testStream is a server stream carrying only a context
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context { return s.ctx }
+
+func withAuthorization(values ...string) context.Context {
+	md := metadata.MD{}
+	for _, v := range values {
+		md.Append("authorization", v)
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+// principalHandler records the caller seen by the handler
+func principalHandler(seen **auth.Principal) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*seen, _ = auth.PrincipalFromContext(ctx)
+		return "ok", nil
+	}
+}
+
+func TestAuthUnaryInterceptorRejects(t *testing.T) {
+	interceptor := AuthUnaryInterceptor(testAuthenticator, testPublicMethods)
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"no metadata", context.Background()},
+		{"no authorization header", metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "1"))},
+		{"basic scheme", withAuthorization("Basic dXNlcjpwYXNz")},
+		{"scheme only", withAuthorization("Bearer")},
+		{"empty token", withAuthorization("Bearer   ")},
+		{"unknown token", withAuthorization("Bearer forged-token")},
+		{"revoked token", withAuthorization("Bearer revoked-token")},
+	}
+	for _, tt := range tests {
+		called := false
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		}
+		_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: protectedMethod}, handler)
+		if !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", tt.name, err)
+		}
+		if called {
+			t.Errorf("%s: handler was called", tt.name)
+		}
+	}
+}
+
+func TestAuthUnaryInterceptorStoresPrincipal(t *testing.T) {
+	interceptor := AuthUnaryInterceptor(testAuthenticator, testPublicMethods)
+
+	var seen *auth.Principal
+	for _, header := range []string{"Bearer good-token", "bearer  good-token "} {
+		seen = nil
+		_, err := interceptor(withAuthorization(header), nil, &grpc.UnaryServerInfo{FullMethod: protectedMethod}, principalHandler(&seen))
+		if err != nil {
+			t.Fatalf("%q: %v", header, err)
+		}
+		if seen == nil || seen.UserID != "usr_1" {
+			t.Errorf("%q: handler saw principal %+v", header, seen)
+		}
+	}
+}
+
+func TestAuthUnaryInterceptorPublicMethods(t *testing.T) {
+	interceptor := AuthUnaryInterceptor(testAuthenticator, testPublicMethods)
+
+	// Public methods skip authentication even with a bad token
+	for _, ctx := range []context.Context{context.Background(), withAuthorization("Bearer forged-token")} {
+		var seen *auth.Principal
+		resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: publicMethod}, principalHandler(&seen))
+		if err != nil || resp != "ok" {
+			t.Errorf("public method = %v, %v", resp, err)
+		}
+		if seen != nil {
+			t.Errorf("public method carries principal %+v", seen)
+		}
+	}
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	interceptor := AuthStreamInterceptor(testAuthenticator, testPublicMethods)
+	info := &grpc.StreamServerInfo{FullMethod: "/userext.UserExtService/ExportUsers", IsServerStream: true}
+
+	var seen *auth.Principal
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		seen, _ = auth.PrincipalFromContext(ss.Context())
+		return nil
+	}
+
+	if err := interceptor(nil, &testStream{ctx: withAuthorization("Bearer good-token")}, info, handler); err != nil {
+		t.Fatalf("stream with a valid token: %v", err)
+	}
+	if seen == nil || seen.UserID != "usr_1" {
+		t.Errorf("stream handler saw principal %+v", seen)
+	}
+
+	seen = nil
+	err := interceptor(nil, &testStream{ctx: withAuthorization("Bearer forged-token")}, info, handler)
+	if !errors.Is(err, model.ErrInvalidToken) || seen != nil {
+		t.Errorf("stream with an unknown token = %v, handler saw %+v", err, seen)
+	}
+	err = interceptor(nil, &testStream{ctx: context.Background()}, info, handler)
+	if !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("stream without a token = %v, want ErrInvalidToken", err)
+	}
+}
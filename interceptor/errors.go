@@ -0,0 +1,119 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// errorDomain identifies this service in ErrorInfo details
+const errorDomain = "user.foodbuddy"
+
+type errorMapping struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// errorMappings lists the domain errors clients can act on. Errors are
+// matched with errors.Is in order, so wrapped sentinels are recognised too.
+var errorMappings = []errorMapping{
+	{model.ErrUserNotFound, codes.NotFound, "USER_NOT_FOUND"},
+	{model.ErrAddressNotFound, codes.NotFound, "ADDRESS_NOT_FOUND"},
+	{model.ErrSessionNotFound, codes.NotFound, "SESSION_NOT_FOUND"},
+	{model.ErrDuplicateEmail, codes.AlreadyExists, "DUPLICATE_EMAIL"},
+	{model.ErrUserAlreadyBanned, codes.FailedPrecondition, "USER_ALREADY_BANNED"},
+	{model.ErrIdempotencyReused, codes.AlreadyExists, "IDEMPOTENCY_KEY_REUSED"},
+	{model.ErrInvalidPassword, codes.Unauthenticated, "INVALID_PASSWORD"},
+	{model.ErrInvalidToken, codes.Unauthenticated, "INVALID_TOKEN"},
+	{model.ErrRefreshTokenReused, codes.Unauthenticated, "REFRESH_TOKEN_REUSED"},
+	{model.ErrUserBanned, codes.PermissionDenied, "USER_BANNED"},
+	{model.ErrPermissionDenied, codes.PermissionDenied, "PERMISSION_DENIED"},
+	{model.ErrUserNotVerified, codes.FailedPrecondition, "USER_NOT_VERIFIED"},
+	{model.ErrCodeExpired, codes.FailedPrecondition, "CODE_EXPIRED"},
+	{model.ErrTooManyAttempts, codes.ResourceExhausted, "TOO_MANY_ATTEMPTS"},
+	{model.ErrAddressLimit, codes.ResourceExhausted, "ADDRESS_LIMIT_REACHED"},
+	{model.ErrInvalidCode, codes.InvalidArgument, "INVALID_CODE"},
+	{model.ErrWeakPassword, codes.InvalidArgument, "WEAK_PASSWORD"},
+	{model.ErrInvalidRole, codes.InvalidArgument, "INVALID_ROLE"},
+	{model.ErrInvalidArgument, codes.InvalidArgument, "INVALID_ARGUMENT"},
+	{model.ErrTokenGeneration, codes.Internal, "TOKEN_GENERATION_FAILED"},
+	{context.Canceled, codes.Canceled, "CANCELED"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+}
+
+// ErrorUnaryInterceptor converts errors returned by handlers into gRPC status
+// errors with a matching code and an ErrorInfo detail. Unrecognised errors are
+// logged and reported as Internal without leaking their message.
+func ErrorUnaryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, toStatusError(ctx, logger, err)
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamInterceptor is the streaming counterpart of ErrorUnaryInterceptor
+func ErrorStreamInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatusError(ss.Context(), logger, err)
+		}
+		return nil
+	}
+}
+
+func toStatusError(ctx context.Context, logger *slog.Logger, err error) error {
+	// Errors that already carry a status are passed through untouched
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return withErrorInfo(status.New(m.code, err.Error()), m.reason, badRequest(err)...)
+		}
+	}
+
+	logger.ErrorContext(ctx, "unhandled error", "error", err)
+	return withErrorInfo(status.New(codes.Internal, "internal server error"), "INTERNAL")
+}
+
+func withErrorInfo(st *status.Status, reason string, details ...protoadapt.MessageV1) error {
+	details = append([]protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	}}, details...)
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// badRequest returns a BadRequest detail listing the invalid fields of a
+// model.ValidationError, or nothing for other errors
+func badRequest(err error) []protoadapt.MessageV1 {
+	var validationErr *model.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	detail := &errdetails.BadRequest{}
+	for _, v := range validationErr.Violations {
+		detail.FieldViolations = append(detail.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	return []protoadapt.MessageV1{detail}
+}
@@ -0,0 +1,104 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func errorInfo(t *testing.T, st *status.Status) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("status %v has no ErrorInfo", st)
+	return nil
+}
+
+func TestToStatusError(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{model.ErrUserNotFound, codes.NotFound, "USER_NOT_FOUND"},
+		{fmt.Errorf("failed to create user: %w", model.ErrDuplicateEmail), codes.AlreadyExists, "DUPLICATE_EMAIL"},
+		{fmt.Errorf("%w: token expired", model.ErrInvalidToken), codes.Unauthenticated, "INVALID_TOKEN"},
+		{model.ErrUserBanned, codes.PermissionDenied, "USER_BANNED"},
+		{fmt.Errorf("%w: BanUser requires more privileges", model.ErrPermissionDenied), codes.PermissionDenied, "PERMISSION_DENIED"},
+		{fmt.Errorf("%w: verification code sent too often", model.ErrTooManyAttempts), codes.ResourceExhausted, "TOO_MANY_ATTEMPTS"},
+		{model.ErrCodeExpired, codes.FailedPrecondition, "CODE_EXPIRED"},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+		{fmt.Errorf("failed to list users: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+		{context.Canceled, codes.Canceled, "CANCELED"},
+		{fmt.Errorf("failed to export users: %w", context.Canceled), codes.Canceled, "CANCELED"},
+	}
+	for _, tt := range tests {
+		st := status.Convert(toStatusError(context.Background(), testLogger, tt.err))
+		if st.Code() != tt.code {
+			t.Errorf("%v: code = %v, want %v", tt.err, st.Code(), tt.code)
+		}
+		if st.Message() != tt.err.Error() {
+			t.Errorf("%v: message = %q", tt.err, st.Message())
+		}
+		info := errorInfo(t, st)
+		if info.Reason != tt.reason || info.Domain != errorDomain {
+			t.Errorf("%v: ErrorInfo = %s/%s, want %s/%s", tt.err, info.Domain, info.Reason, errorDomain, tt.reason)
+		}
+	}
+}
+
+func TestToStatusErrorHidesUnknownErrors(t *testing.T) {
+	err := errors.New("dial tcp 10.0.0.5:3306: connection refused")
+	st := status.Convert(toStatusError(context.Background(), testLogger, err))
+	if st.Code() != codes.Internal || st.Message() != "internal server error" {
+		t.Errorf("unknown error = %v: %q", st.Code(), st.Message())
+	}
+	if info := errorInfo(t, st); info.Reason != "INTERNAL" {
+		t.Errorf("reason = %q, want INTERNAL", info.Reason)
+	}
+}
+
+func TestToStatusErrorKeepsStatusErrors(t *testing.T) {
+	err := status.Error(codes.Unavailable, "draining")
+	if got := toStatusError(context.Background(), testLogger, err); got != err {
+		t.Errorf("status error was rewritten to %v", got)
+	}
+}
+
+func TestToStatusErrorListsFieldViolations(t *testing.T) {
+	err := fmt.Errorf("invalid address: %w", &model.ValidationError{Violations: []model.FieldViolation{
+		{Field: "pincode", Description: "must be 6 digits"},
+		{Field: "state", Description: "is required"},
+	}})
+	st := status.Convert(toStatusError(context.Background(), testLogger, err))
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v, want InvalidArgument", st.Code())
+	}
+	if info := errorInfo(t, st); info.Reason != "INVALID_ARGUMENT" {
+		t.Errorf("reason = %q, want INVALID_ARGUMENT", info.Reason)
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	if len(violations) != 2 || violations[0].Field != "pincode" || violations[1].Description != "is required" {
+		t.Errorf("field violations = %v", violations)
+	}
+}
@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/logging"
+)
+
+// RequestIDHeader carries the request ID in incoming and outgoing metadata
+const RequestIDHeader = "x-request-id"
+
+// LoggingUnaryInterceptor tags the context with a request ID, taken from the
+// x-request-id metadata or generated, echoes it in the response header and logs
+// each finished RPC with its status code and duration. Request and response
+// payloads are never logged. It must run before ErrorUnaryInterceptor so it sees
+// the final status code.
+func LoggingUnaryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestID(ctx, info.FullMethod)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRPC(ctx, logger, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor is the streaming counterpart of LoggingUnaryInterceptor
+func LoggingStreamInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context(), info.FullMethod)
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logRPC(ctx, logger, start, err)
+		return err
+	}
+}
+
+func withRequestID(ctx context.Context, method string) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	// Best effort, the header cannot be set once the response has started
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+	return logging.WithRequest(ctx, requestID, method)
+}
+
+func logRPC(ctx context.Context, logger *slog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("status", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	logger.LogAttrs(ctx, levelForCode(code), "rpc finished", attrs...)
+}
+
+// levelForCode logs server faults as errors and client mistakes as warnings
+func levelForCode(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
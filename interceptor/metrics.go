@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/metrics"
+)
+
+// MetricsUnaryInterceptor records the count, status code and latency of unary RPCs.
+// It must run before ErrorUnaryInterceptor so it sees the final status code.
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// MetricsStreamInterceptor records the count, status code and duration of streaming RPCs
+func MetricsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeRPC(fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	metrics.RPCRequests.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	metrics.RPCDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod turns "/user.UserService/UserLogin" into "user.UserService" and "UserLogin"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
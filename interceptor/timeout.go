@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// TimeoutUnaryInterceptor gives each unary RPC a deadline of defaultTimeout,
+// or the override for its method name in perMethod. A zero timeout leaves the
+// context untouched. Deadlines set by the client still apply when shorter.
+func TimeoutUnaryInterceptor(defaultTimeout time.Duration, perMethod map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		timeout, ok := perMethod[path.Base(info.FullMethod)]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// TimeoutStreamInterceptor applies per-method deadlines to streaming RPCs.
+// Streams have no default deadline since exports may legitimately run long.
+func TimeoutStreamInterceptor(perMethod map[string]time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		timeout := perMethod[path.Base(info.FullMethod)]
+		if timeout <= 0 {
+			return handler(srv, ss)
+		}
+
+		ctx, cancel := context.WithTimeout(ss.Context(), timeout)
+		defer cancel()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream overrides the context of a grpc.ServerStream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,101 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// deadlineHandler records the time left before the deadline of the context
+// it is called with, or -1 when there is none
+func deadlineHandler(left *time.Duration) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*left = -1
+		if deadline, ok := ctx.Deadline(); ok {
+			*left = time.Until(deadline)
+		}
+		return nil, nil
+	}
+}
+
+func TestTimeoutUnaryInterceptor(t *testing.T) {
+	interceptor := TimeoutUnaryInterceptor(time.Minute, map[string]time.Duration{
+		"ExportUsers": time.Hour,
+		"UserLogin":   0,
+	})
+	shortCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		min, max time.Duration
+	}{
+		{"default", context.Background(), "/user.UserService/GetProfile", 59 * time.Second, time.Minute},
+		{"override", context.Background(), "/userext.UserExtService/ExportUsers", 59 * time.Minute, time.Hour},
+		{"disabled", context.Background(), "/user.UserService/UserLogin", -1, -1},
+		{"shorter client deadline", shortCtx, "/user.UserService/GetProfile", 0, time.Second},
+	}
+	for _, tt := range tests {
+		var left time.Duration
+		if _, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, deadlineHandler(&left)); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if left < tt.min || left > tt.max {
+			t.Errorf("%s: time left = %v, want between %v and %v", tt.name, left, tt.min, tt.max)
+		}
+	}
+}
+
+func TestTimeoutUnaryInterceptorDeadlineExceeded(t *testing.T) {
+	timeout := TimeoutUnaryInterceptor(10*time.Millisecond, nil)
+	errs := ErrorUnaryInterceptor(testLogger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetProfile"}
+
+	// A handler outliving its deadline reports it as the gRPC server sees it
+	slow := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	_, err := errs(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return timeout(ctx, req, info, slow)
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("slow handler = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestTimeoutStreamInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/userext.UserExtService/ExportUsers", IsServerStream: true}
+	tests := []struct {
+		name      string
+		perMethod map[string]time.Duration
+		min, max  time.Duration
+	}{
+		{"override", map[string]time.Duration{"ExportUsers": time.Minute}, 59 * time.Second, time.Minute},
+		// Streams have no default deadline
+		{"no override", nil, -1, -1},
+	}
+	for _, tt := range tests {
+		var left time.Duration
+		handler := func(srv interface{}, ss grpc.ServerStream) error {
+			left = -1
+			if deadline, ok := ss.Context().Deadline(); ok {
+				left = time.Until(deadline)
+			}
+			return nil
+		}
+		interceptor := TimeoutStreamInterceptor(tt.perMethod)
+		if err := interceptor(nil, &testStream{ctx: context.Background()}, info, handler); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if left < tt.min || left > tt.max {
+			t.Errorf("%s: time left = %v, want between %v and %v", tt.name, left, tt.min, tt.max)
+		}
+	}
+}
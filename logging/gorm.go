@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM logs to slog. Statements are logged with placeholders
+// instead of bound values so query parameters never reach the logs.
+// Failed queries are logged at error level, slow ones at warn and the rest at debug.
+type GormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+// NewGormLogger returns a GORM logger that reports queries slower than slowThreshold as warnings
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: logger, slowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	level := slog.LevelDebug
+	msg := "query"
+	switch {
+	// Missing records are an expected outcome reported by the repository
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		level, msg = slog.LevelError, "query failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops bound values so statements are logged with placeholders
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
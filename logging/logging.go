@@ -0,0 +1,63 @@
+// Package logging builds the structured logger shared by the service. Every
+// record passes through a redaction layer and is tagged with the request ID
+// and RPC method carried by its context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New returns a logger writing to w in the given format ("json" or "text")
+// at the given level ("debug", "info", "warn" or "error")
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(NewRedactingHandler(handler)), nil
+}
+
+type requestKey struct{}
+
+type requestInfo struct {
+	id     string
+	method string
+}
+
+// WithRequest stores the request ID and RPC method in ctx. Records logged
+// with that context, or one derived from it, carry both as attributes.
+func WithRequest(ctx context.Context, requestID, method string) context.Context {
+	return context.WithValue(ctx, requestKey{}, requestInfo{id: requestID, method: method})
+}
+
+// RequestID returns the request ID stored in ctx, or "" when there is none
+func RequestID(ctx context.Context) string {
+	info, _ := ctx.Value(requestKey{}).(requestInfo)
+	return info.id
+}
+
+func requestAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	info, ok := ctx.Value(requestKey{}).(requestInfo)
+	if !ok {
+		return nil
+	}
+	return []slog.Attr{slog.String("request_id", info.id), slog.String("method", info.method)}
+}
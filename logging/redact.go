@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute key fragments whose values are never logged
+var secretKeys = []string{"password", "code", "token", "secret", "otp", "body"}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// RedactingHandler masks personal data before records reach the wrapped handler:
+//   - attributes whose key mentions a password, code, token or secret are replaced
+//   - attributes whose key mentions an email or phone are partially masked
+//   - email addresses anywhere in the message or string values are masked
+//
+// It also adds the request ID and method from the record's context.
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler wraps next with redaction
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	clean := slog.NewRecord(record.Time, record.Level, maskEmails(record.Message), record.PC)
+	clean.AddAttrs(requestAttrs(ctx)...)
+	record.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	key := strings.ToLower(a.Key)
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, g := range group {
+			clean[i] = redactAttr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	}
+
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	switch {
+	case strings.Contains(key, "email"):
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	case strings.Contains(key, "phone"):
+		return slog.String(a.Key, MaskPhone(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, maskEmails(a.Value.String()))
+	case slog.KindAny:
+		// Errors and other values are logged by their text, which may embed an address
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, maskEmails(err.Error()))
+		}
+	}
+	return a
+}
+
+// MaskEmail keeps the first character of the local part and the domain,
+// turning "jane.doe@example.com" into "j***@example.com"
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+// MaskPhone keeps only the last two digits of a phone number
+func MaskPhone(phone string) string {
+	if len(phone) <= 2 {
+		return redacted
+	}
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
+
+func maskEmails(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
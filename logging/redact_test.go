@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactingHandlerMasksPersonalData(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "debug")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx := WithRequest(context.Background(), "req-1", "/user.UserService/UserLogin")
+	logger.With("email", "jane.doe@example.com").InfoContext(ctx, "login for jane.doe@example.com",
+		"password", "hunter2hunter2",
+		"verification_code", "123456",
+		"refresh_token", "opaque-token",
+		"phone_number", uint64(9876543210),
+		"error", errors.New("user bob@example.org not found"),
+		slog.Group("request", slog.String("new_email", "x@example.net"), slog.String("name", "Jane")),
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"jane.doe@", "hunter2", "123456", "opaque-token", "9876543210", "bob@", "x@example.net"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("log output leaks %q: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{`"request_id":"req-1"`, `"method":"/user.UserService/UserLogin"`, "j***@example.com", "********10", `"name":"Jane"`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("log output is missing %q: %s", kept, out)
+		}
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("New accepted an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("New accepted an unknown format")
+	}
+}
@@ -0,0 +1,87 @@
+// Package metrics defines the Prometheus metrics exported by the user service
+// and the HTTP handler that serves them.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "foodbuddy_user"
+
+// Registry holds every metric of the service. A dedicated registry keeps
+// metrics registered by imported libraries out of the endpoint.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RPCRequests counts finished RPCs by gRPC method and status code
+	RPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of RPCs handled, by method and status code.",
+	}, []string{"service", "method", "code"})
+
+	// RPCDuration observes the handling time of RPCs by gRPC method
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Time taken to handle RPCs, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	// Signups counts successfully created accounts
+	Signups = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signups_total",
+		Help:      "Number of accounts created.",
+	})
+
+	// Logins counts successful logins
+	Logins = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of successful logins.",
+	})
+
+	// FailedLogins counts logins rejected for an unknown email or a wrong password
+	FailedLogins = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failed_logins_total",
+		Help:      "Number of logins rejected because of invalid credentials.",
+	})
+
+	// Bans counts users banned
+	Bans = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bans_total",
+		Help:      "Number of users banned.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RPCRequests,
+		RPCDuration,
+		Signups,
+		Logins,
+		FailedLogins,
+		Bans,
+	)
+}
+
+// RegisterDBStats exports the sql.DBStats of the connection pool, such as open
+// and in-use connections and the wait count and duration
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -1,6 +1,9 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	ErrUserNotFound    = errors.New("user not found")
@@ -10,4 +13,50 @@ var (
 	ErrUserNotVerified = errors.New("user not verified")
 	ErrInvalidCode     = errors.New("invalid verification code")
 	ErrDuplicateEmail  = errors.New("email already exists")
+	ErrCodeExpired     = errors.New("verification code expired")
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+	ErrWeakPassword    = errors.New("password does not meet requirements")
+
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+	ErrAddressNotFound   = errors.New("address not found or does not belong to user")
+	ErrAddressLimit      = errors.New("address limit reached")
+	ErrUserAlreadyBanned = errors.New("user is already banned")
+	ErrUserBanned        = errors.New("user is banned")
+	ErrIdempotencyReused = errors.New("idempotency key reused with a different request")
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidArgument   = errors.New("invalid argument")
 )
+
+// FieldViolation describes why one field of a request is invalid. Field is
+// the path of the field in the request, such as "address.pincode".
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is an ErrInvalidArgument that lists every invalid field, so
+// clients can point at them instead of parsing the message
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrInvalidArgument.Error())
+	for i, v := range e.Violations {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(v.Field + " " + v.Description)
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidArgument
+}
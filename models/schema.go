@@ -1,22 +1,173 @@
 package model
 
+import "time"
+
+// Role grants a set of permissions. Every user implicitly has RoleUser;
+// the other roles are granted explicitly and stored as UserRole rows.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleSupport   Role = "support"
+	RoleAdmin     Role = "admin"
+	// RoleService is held by the accounts other FoodBuddy services call us with
+	RoleService Role = "service"
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleSupport, RoleAdmin, RoleService:
+		return true
+	}
+	return false
+}
+
 type User struct {
-	ID               string `gorm:"primaryKey;type:varchar(255)"`
-	Email            string `gorm:"type:varchar(255);uniqueIndex"`
-	PasswordHash     string `gorm:"type:varchar(255)"`
-	Name             string `gorm:"type:varchar(255)"`
-	PhoneNumber      uint64
-	Reputation       int32
-	VerificationCode string `gorm:"type:varchar(255)"`
-	IsBanned         bool
-	IsVerified       bool
+	ID           string `gorm:"primaryKey;type:varchar(255)"`
+	Email        string `gorm:"type:varchar(255);uniqueIndex"`
+	PasswordHash string `gorm:"type:varchar(255)"`
+	Name         string `gorm:"type:varchar(255)"`
+	PhoneNumber  uint64
+	// The leaderboard index serves rankings, which skip banned users
+	Reputation int32 `gorm:"index:idx_users_leaderboard,priority:2"`
+	IsBanned   bool  `gorm:"index:idx_users_leaderboard,priority:1"`
+	IsVerified bool
+	CreatedAt  time.Time `gorm:"index"`
+	// TokenVersion is copied into access tokens and bumped when the password
+	// changes, so tokens issued before the change stop working
+	TokenVersion int
+
+	// VerificationCode holds the hash of the pending email verification code
+	VerificationCode      string `gorm:"type:varchar(255)"`
+	VerificationExpiresAt *time.Time
+	VerificationAttempts  int
+	// VerificationSentAt and VerificationSends throttle resent codes, since
+	// each new code comes with a fresh set of attempts
+	VerificationSentAt *time.Time
+	VerificationSends  int
+
+	// PendingEmail is the new address requested by ChangeEmail until it is confirmed
+	PendingEmail         string `gorm:"type:varchar(255)"`
+	EmailChangeCode      string `gorm:"type:varchar(255)"`
+	EmailChangeExpiresAt *time.Time
+	EmailChangeAttempts  int
+
+	// PasswordResetSentAt and PasswordResetSends throttle reset emails, since
+	// each one invalidates the previous reset token
+	PasswordResetSentAt *time.Time
+	PasswordResetSends  int
+}
+
+// AddressLabel tells a user's addresses apart at checkout
+type AddressLabel string
+
+const (
+	AddressLabelHome  AddressLabel = "home"
+	AddressLabelWork  AddressLabel = "work"
+	AddressLabelOther AddressLabel = "other"
+)
+
+// Valid reports whether l is a known label
+func (l AddressLabel) Valid() bool {
+	switch l {
+	case AddressLabelHome, AddressLabelWork, AddressLabelOther:
+		return true
+	}
+	return false
 }
 
 type UserAddress struct {
-	ID         string `gorm:"primaryKey;type:varchar(255)"`
-	UserID     string `gorm:"type:varchar(255)"`
+	ID string `gorm:"primaryKey;type:varchar(255)"`
+	// UserID references users.id; a user's addresses are deleted with them
+	UserID     string `gorm:"type:varchar(255);index"`
 	StreetName string `gorm:"type:varchar(255)"`
 	Locality   string `gorm:"type:varchar(255)"`
 	State      string `gorm:"type:varchar(255)"`
 	Pincode    string `gorm:"type:varchar(6)"`
+
+	Label AddressLabel `gorm:"type:varchar(16)"`
+	// IsDefault marks the address checkout uses unless told otherwise. Every
+	// user with addresses has exactly one default.
+	IsDefault bool
+	// Landmark, FlatNumber and DeliveryInstructions help couriers find the door.
+	// FlatNumber holds the flat and floor, such as "4B, 2nd floor".
+	Landmark             string `gorm:"type:varchar(255)"`
+	FlatNumber           string `gorm:"type:varchar(64)"`
+	DeliveryInstructions string `gorm:"type:varchar(500)"`
+	CreatedAt            time.Time
+
+	// Latitude and Longitude are filled in by geocoding and are nil when the
+	// address could not be located
+	Latitude  *float64 `gorm:"type:double precision"`
+	Longitude *float64 `gorm:"type:double precision"`
+}
+
+// Session is a refresh-token session for one device. Every rotation creates a
+// new Session in the same family and marks the previous one as rotated.
+type Session struct {
+	ID               string `gorm:"primaryKey;type:varchar(255)"`
+	UserID           string `gorm:"type:varchar(255);index"`
+	FamilyID         string `gorm:"type:varchar(255);index"`
+	RefreshTokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	DeviceInfo       string `gorm:"type:varchar(255)"`
+	ExpiresAt        time.Time
+	RotatedAt        *time.Time
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their
+// password. Only the hash of the token is stored.
+type PasswordResetToken struct {
+	ID        string `gorm:"primaryKey;type:varchar(255)"`
+	UserID    string `gorm:"type:varchar(255);index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// UserRole is a role granted to a user, recording who granted it
+type UserRole struct {
+	UserID    string `gorm:"primaryKey;type:varchar(255)"`
+	Role      Role   `gorm:"primaryKey;type:varchar(32)"`
+	GrantedBy string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+}
+
+// UserBan records one ban of a user. User.IsBanned caches whether the user has
+// an active ban, which is one that has not been lifted and has not expired.
+type UserBan struct {
+	ID        string `gorm:"primaryKey;type:varchar(255)"`
+	UserID    string `gorm:"type:varchar(255);index"`
+	Reason    string `gorm:"type:varchar(1024)"`
+	BannedBy  string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+	// ExpiresAt is nil for permanent bans
+	ExpiresAt *time.Time
+	// LiftedAt is set when the ban is lifted by LiftedBy, or to ExpiresAt with
+	// an empty LiftedBy when it lapsed
+	LiftedAt *time.Time
+	LiftedBy string `gorm:"type:varchar(255)"`
+}
+
+// Active reports whether the ban still applies at now
+func (b *UserBan) Active(now time.Time) bool {
+	return b.LiftedAt == nil && (b.ExpiresAt == nil || now.Before(*b.ExpiresAt))
+}
+
+// ReputationEvent is one entry of the append-only reputation ledger.
+// User.Reputation caches the sum of a user's deltas. IdempotencyKey is unique
+// per SourceService so retried calls are recorded once.
+type ReputationEvent struct {
+	ID             string `gorm:"primaryKey;type:varchar(255)"`
+	UserID         string `gorm:"type:varchar(255);index"`
+	Delta          int32
+	Reason         string `gorm:"type:varchar(255)"`
+	SourceService  string `gorm:"type:varchar(64);uniqueIndex:idx_reputation_events_idempotency"`
+	ReferenceID    string `gorm:"type:varchar(255)"`
+	IdempotencyKey string `gorm:"type:varchar(255);uniqueIndex:idx_reputation_events_idempotency"`
+	CreatedAt      time.Time
 }
@@ -0,0 +1,78 @@
+// Package postal validates Indian postal addresses against an embedded
+// directory of pincodes and the states and districts they belong to.
+package postal
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed data/pincodes.csv
+var directoryData []byte
+
+// Place is where a pincode delivers to. District and Locality are empty when
+// the directory only knows the state.
+type Place struct {
+	State    string
+	District string
+	Locality string
+}
+
+// directory maps pincode prefixes to places. It is parsed once at startup and
+// the embedded data is checked by the tests, so a bad row cannot ship.
+var directory = mustLoadDirectory(directoryData)
+
+func mustLoadDirectory(data []byte) map[string]Place {
+	places, err := loadDirectory(data)
+	if err != nil {
+		panic(err)
+	}
+	return places
+}
+
+func loadDirectory(data []byte) (map[string]Place, error) {
+	places := make(map[string]Place)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("pincode directory line %d: want prefix,state,district,locality", line)
+		}
+		prefix := fields[0]
+		if len(prefix) < 2 || len(prefix) > PincodeLength || strings.Trim(prefix, "0123456789") != "" {
+			return nil, fmt.Errorf("pincode directory line %d: invalid prefix %q", line, prefix)
+		}
+		if state, ok := NormalizeState(fields[1]); !ok || state != fields[1] {
+			return nil, fmt.Errorf("pincode directory line %d: %q is not a canonical state name", line, fields[1])
+		}
+		if _, ok := places[prefix]; ok {
+			return nil, fmt.Errorf("pincode directory line %d: duplicate prefix %s", line, prefix)
+		}
+		places[prefix] = Place{State: fields[1], District: fields[2], Locality: fields[3]}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pincode directory: %w", err)
+	}
+	return places, nil
+}
+
+// Lookup returns the place of a six digit pincode, using the most specific
+// directory entry that matches it
+func Lookup(pincode string) (Place, bool) {
+	if !ValidPincode(pincode) {
+		return Place{}, false
+	}
+	for n := PincodeLength; n >= 2; n-- {
+		if place, ok := directory[pincode[:n]]; ok {
+			return place, true
+		}
+	}
+	return Place{}, false
+}
@@ -0,0 +1,159 @@
+package postal
+
+import (
+	"reflect"
+	"testing"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+func TestLoadDirectory(t *testing.T) {
+	// The embedded directory is loaded at init; this catches a bad row with a
+	// readable error instead of a panic
+	if _, err := loadDirectory(directoryData); err != nil {
+		t.Fatalf("embedded directory: %v", err)
+	}
+
+	for _, data := range []string{
+		"68,Kerala,",
+		"6,Kerala,,",
+		"68x,Kerala,,",
+		"68,Kerela,,",
+		"68,kerala,,",
+		"68,Kerala,,\n68,Kerala,,",
+	} {
+		if _, err := loadDirectory([]byte(data)); err == nil {
+			t.Errorf("loadDirectory(%q) accepted invalid data", data)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		pincode string
+		want    Place
+		ok      bool
+	}{
+		{"682016", Place{State: "Kerala", District: "Ernakulam", Locality: "Ernakulam South"}, true},
+		// Falls back to the district, then the state
+		{"695014", Place{State: "Kerala", District: "Thiruvananthapuram"}, true},
+		{"680001", Place{State: "Kerala"}, true},
+		// Longer prefixes win over the state of the first two digits
+		{"682557", Place{State: "Lakshadweep", District: "Lakshadweep"}, true},
+		{"403001", Place{State: "Goa"}, true},
+		{"834001", Place{State: "Jharkhand"}, true},
+		{"160017", Place{State: "Chandigarh", District: "Chandigarh"}, true},
+		{"160055", Place{State: "Punjab"}, true},
+		{"999999", Place{}, false},
+		{"68201", Place{}, false},
+		{"068201", Place{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Lookup(tt.pincode)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%q) = %+v, %v, want %+v, %v", tt.pincode, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeState(t *testing.T) {
+	tests := map[string]string{
+		"Kerala":            "Kerala",
+		"  kerala ":         "Kerala",
+		"TAMIL NADU":        "Tamil Nadu",
+		"Tamilnadu":         "Tamil Nadu",
+		"J&K":               "Jammu and Kashmir",
+		"Jammu & Kashmir":   "Jammu and Kashmir",
+		"Orissa":            "Odisha",
+		"KA":                "Karnataka",
+		"U.P.":              "Uttar Pradesh",
+		"NCT of Delhi":      "Delhi",
+		"Pondicherry":       "Puducherry",
+		"Daman and Diu":     "Dadra and Nagar Haveli and Daman and Diu",
+		"andaman & nicobar": "Andaman and Nicobar Islands",
+	}
+	for in, want := range tests {
+		if got, ok := NormalizeState(in); !ok || got != want {
+			t.Errorf("NormalizeState(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "Kerela", "California", "XX"} {
+		if got, ok := NormalizeState(in); ok {
+			t.Errorf("NormalizeState(%q) = %q, want not ok", in, got)
+		}
+	}
+	for _, name := range States {
+		if got, ok := NormalizeState(name); !ok || got != name {
+			t.Errorf("canonical name %q normalises to %q, %v", name, got, ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         model.UserAddress
+		want       model.UserAddress
+		violations []model.FieldViolation
+	}{
+		{
+			name: "canonical address is unchanged",
+			in:   model.UserAddress{Locality: "Panampilly Nagar", State: "Kerala", Pincode: "682036"},
+			want: model.UserAddress{Locality: "Panampilly Nagar", State: "Kerala", Pincode: "682036"},
+		},
+		{
+			name: "state and pincode are normalised",
+			in:   model.UserAddress{Locality: "Koramangala", State: " karnataka", Pincode: "560 034"},
+			want: model.UserAddress{Locality: "Koramangala", State: "Karnataka", Pincode: "560034"},
+		},
+		{
+			name: "locality and state are filled in",
+			in:   model.UserAddress{Pincode: "682016"},
+			want: model.UserAddress{Locality: "Ernakulam South", State: "Kerala", Pincode: "682016"},
+		},
+		{
+			name: "district stands in for an unknown locality",
+			in:   model.UserAddress{Pincode: "560095"},
+			want: model.UserAddress{Locality: "Bengaluru Urban", State: "Karnataka", Pincode: "560095"},
+		},
+		{
+			name:       "state must match the pincode",
+			in:         model.UserAddress{State: "Karnataka", Pincode: "682016"},
+			want:       model.UserAddress{Locality: "Ernakulam South", State: "Karnataka", Pincode: "682016"},
+			violations: []model.FieldViolation{{Field: "state", Description: "does not match pincode 682016, which is in Kerala"}},
+		},
+		{
+			name: "every invalid field is reported",
+			in:   model.UserAddress{State: "Kerela", Pincode: "68201"},
+			want: model.UserAddress{State: "Kerela", Pincode: "68201"},
+			violations: []model.FieldViolation{
+				{Field: "state", Description: "is not a state or union territory of India"},
+				{Field: "pincode", Description: "must be six digits and not start with 0"},
+			},
+		},
+		{
+			name:       "pincode is required",
+			in:         model.UserAddress{State: "Kerala"},
+			want:       model.UserAddress{State: "Kerala"},
+			violations: []model.FieldViolation{{Field: "pincode", Description: "is required"}},
+		},
+		{
+			name:       "pincode must be known",
+			in:         model.UserAddress{Pincode: "990001"},
+			want:       model.UserAddress{Pincode: "990001"},
+			violations: []model.FieldViolation{{Field: "pincode", Description: "is not a known pincode"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			violations := Normalize(&got)
+			if !reflect.DeepEqual(violations, tt.violations) {
+				t.Errorf("violations = %+v, want %+v", violations, tt.violations)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("address = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
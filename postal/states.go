@@ -0,0 +1,73 @@
+package postal
+
+import "strings"
+
+// States lists the canonical names of India's states and union territories
+var States = []string{
+	"Andhra Pradesh", "Arunachal Pradesh", "Assam", "Bihar", "Chhattisgarh",
+	"Goa", "Gujarat", "Haryana", "Himachal Pradesh", "Jharkhand", "Karnataka",
+	"Kerala", "Madhya Pradesh", "Maharashtra", "Manipur", "Meghalaya",
+	"Mizoram", "Nagaland", "Odisha", "Punjab", "Rajasthan", "Sikkim",
+	"Tamil Nadu", "Telangana", "Tripura", "Uttar Pradesh", "Uttarakhand",
+	"West Bengal",
+	"Andaman and Nicobar Islands", "Chandigarh",
+	"Dadra and Nagar Haveli and Daman and Diu", "Delhi", "Jammu and Kashmir",
+	"Ladakh", "Lakshadweep", "Puducherry",
+}
+
+// stateAliases maps state codes, former names and common spellings to
+// canonical names. Keys are in the form stateKey returns.
+var stateAliases = map[string]string{
+	"ap": "Andhra Pradesh", "ar": "Arunachal Pradesh", "as": "Assam",
+	"br": "Bihar", "cg": "Chhattisgarh", "ct": "Chhattisgarh",
+	"chattisgarh": "Chhattisgarh", "ga": "Goa", "gj": "Gujarat",
+	"hr": "Haryana", "hp": "Himachal Pradesh", "jh": "Jharkhand",
+	"ka": "Karnataka", "kl": "Kerala", "mp": "Madhya Pradesh",
+	"mh": "Maharashtra", "mn": "Manipur", "ml": "Meghalaya", "mz": "Mizoram",
+	"nl": "Nagaland", "od": "Odisha", "or": "Odisha", "orissa": "Odisha",
+	"pb": "Punjab", "rj": "Rajasthan", "sk": "Sikkim", "tn": "Tamil Nadu",
+	"tg": "Telangana", "ts": "Telangana", "tr": "Tripura",
+	"up": "Uttar Pradesh", "uk": "Uttarakhand", "ut": "Uttarakhand",
+	"uttaranchal": "Uttarakhand", "wb": "West Bengal",
+	"an": "Andaman and Nicobar Islands", "aandn": "Andaman and Nicobar Islands",
+	"andamanandnicobar":   "Andaman and Nicobar Islands",
+	"ch":                  "Chandigarh",
+	"dh":                  "Dadra and Nagar Haveli and Daman and Diu",
+	"dadraandnagarhaveli": "Dadra and Nagar Haveli and Daman and Diu",
+	"damananddiu":         "Dadra and Nagar Haveli and Daman and Diu",
+	"dl":                  "Delhi", "newdelhi": "Delhi", "nctofdelhi": "Delhi",
+	"nationalcapitalterritoryofdelhi": "Delhi",
+	"jk":                              "Jammu and Kashmir", "jandk": "Jammu and Kashmir", "la": "Ladakh", "ld": "Lakshadweep",
+	"py": "Puducherry", "pondicherry": "Puducherry",
+}
+
+// statesByKey indexes the canonical names and their aliases by stateKey
+var statesByKey = func() map[string]string {
+	states := make(map[string]string, len(States)+len(stateAliases))
+	for _, name := range States {
+		states[stateKey(name)] = name
+	}
+	for alias, name := range stateAliases {
+		states[alias] = name
+	}
+	return states
+}()
+
+// NormalizeState returns the canonical name of the state or union territory
+// named by s. Case, spacing, punctuation and "&" for "and" are ignored, so
+// "tamilnadu", "J&K" and "Orissa" are all recognised.
+func NormalizeState(s string) (string, bool) {
+	name, ok := statesByKey[stateKey(s)]
+	return name, ok
+}
+
+// stateKey lowercases s and drops everything but its letters
+func stateKey(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "&", "and")
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,82 @@
+package postal
+
+import (
+	"fmt"
+	"strings"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// PincodeLength is the number of digits in an Indian pincode
+const PincodeLength = 6
+
+// ValidPincode reports whether s has the shape of a pincode: six digits, the
+// first of which is not 0
+func ValidPincode(s string) bool {
+	if len(s) != PincodeLength || s[0] == '0' {
+		return false
+	}
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// Normalize checks the pincode and state of address against the directory and
+// rewrites them in canonical form, filling in the state and locality from the
+// pincode when they are empty. It returns a violation for every invalid field,
+// named after the fields of the Address message.
+func Normalize(address *model.UserAddress) []model.FieldViolation {
+	var violations []model.FieldViolation
+	address.Pincode = strings.Join(strings.Fields(address.Pincode), "")
+	address.Locality = strings.TrimSpace(address.Locality)
+	address.State = strings.TrimSpace(address.State)
+
+	stateKnown := true
+	if address.State != "" {
+		state, ok := NormalizeState(address.State)
+		if ok {
+			address.State = state
+		} else {
+			stateKnown = false
+			violations = append(violations, model.FieldViolation{
+				Field:       "state",
+				Description: "is not a state or union territory of India",
+			})
+		}
+	}
+
+	var place Place
+	var found bool
+	switch {
+	case address.Pincode == "":
+		violations = append(violations, model.FieldViolation{Field: "pincode", Description: "is required"})
+	case !ValidPincode(address.Pincode):
+		violations = append(violations, model.FieldViolation{
+			Field:       "pincode",
+			Description: "must be six digits and not start with 0",
+		})
+	default:
+		place, found = Lookup(address.Pincode)
+		if !found {
+			violations = append(violations, model.FieldViolation{Field: "pincode", Description: "is not a known pincode"})
+		}
+	}
+	if !found {
+		return violations
+	}
+
+	switch {
+	case address.State == "":
+		address.State = place.State
+	case stateKnown && address.State != place.State:
+		violations = append(violations, model.FieldViolation{
+			Field:       "state",
+			Description: fmt.Sprintf("does not match pincode %s, which is in %s", address.Pincode, place.State),
+		})
+	}
+	if address.Locality == "" {
+		address.Locality = place.Locality
+		if address.Locality == "" {
+			address.Locality = place.District
+		}
+	}
+	return violations
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+)
+
+// CheckBan reports whether a user has an active ban. An expired temporary ban
+// is lifted on the way, so it lapses even before LiftExpiredBans runs.
+func (r *userRepository) CheckBan(ctx context.Context, userID string) (bool, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Select("is_banned").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, model.ErrUserNotFound
+		}
+		return true, fmt.Errorf("check ban failed: %w", err)
+	}
+	if !user.IsBanned {
+		return false, nil
+	}
+
+	var lifted int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		lifted, err = liftExpiredBans(tx, time.Now(), userID)
+		return err
+	})
+	if err != nil {
+		return true, err
+	}
+	return lifted == 0, nil
+}
+
+// BanUser records ban and marks the user as banned. A user has at most one
+// active ban, so banning a banned user fails with ErrUserAlreadyBanned.
+func (r *userRepository) BanUser(ctx context.Context, ban *model.UserBan) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireUser(tx, ban.UserID); err != nil {
+			return err
+		}
+		if _, err := liftExpiredBans(tx, time.Now(), ban.UserID); err != nil {
+			return err
+		}
+
+		// Only match unbanned rows so RowsAffected means the same on every dialect
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND is_banned = ?", ban.UserID, false).
+			Update("is_banned", true)
+		if result.Error != nil {
+			return fmt.Errorf("failed to ban user: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrUserAlreadyBanned
+		}
+
+		if err := tx.Create(ban).Error; err != nil {
+			return fmt.Errorf("failed to record ban: %w", err)
+		}
+		return nil
+	})
+}
+
+// UnBanUser lifts the active ban of a user, keeping it in the history.
+// Unbanning a user who is not banned is a no-op.
+func (r *userRepository) UnBanUser(ctx context.Context, userID, liftedBy string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireUser(tx, userID); err != nil {
+			return err
+		}
+
+		// A ban that already expired lapsed on its own rather than being lifted
+		now := time.Now()
+		if _, err := liftExpiredBans(tx, now, userID); err != nil {
+			return err
+		}
+		err := tx.Model(&model.UserBan{}).
+			Where("user_id = ? AND lifted_at IS NULL", userID).
+			Updates(map[string]interface{}{"lifted_at": now, "lifted_by": liftedBy}).Error
+		if err != nil {
+			return fmt.Errorf("failed to lift ban: %w", err)
+		}
+
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("is_banned", false).Error; err != nil {
+			return fmt.Errorf("failed to unban user: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetBanHistory returns every ban of a user, newest first
+func (r *userRepository) GetBanHistory(ctx context.Context, userID string) ([]*model.UserBan, error) {
+	if err := requireUser(r.db.WithContext(ctx), userID); err != nil {
+		return nil, err
+	}
+	var bans []*model.UserBan
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC").Order("id DESC").Find(&bans).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ban history: %w", err)
+	}
+	return bans, nil
+}
+
+// LiftExpiredBans lifts every temporary ban that expired by now and returns
+// how many were lifted
+func (r *userRepository) LiftExpiredBans(ctx context.Context, now time.Time) (int, error) {
+	var lifted int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		lifted, err = liftExpiredBans(tx, now, "")
+		return err
+	})
+	return lifted, err
+}
+
+// liftExpiredBans lifts the bans that expired by now, of one user or of all
+// users when userID is empty. A lapsed ban is lifted at its expiry by nobody.
+func liftExpiredBans(tx *gorm.DB, now time.Time, userID string) (int, error) {
+	query := tx.Where("lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", now)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var expired []*model.UserBan
+	if err := query.Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired bans: %w", err)
+	}
+
+	lifted := 0
+	for _, ban := range expired {
+		// Guarded on lifted_at so concurrent callers lift each ban once
+		result := tx.Model(&model.UserBan{}).
+			Where("id = ? AND lifted_at IS NULL", ban.ID).
+			Update("lifted_at", ban.ExpiresAt)
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to lift expired ban: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", ban.UserID).Update("is_banned", false).Error; err != nil {
+			return 0, fmt.Errorf("failed to unban user: %w", err)
+		}
+		lifted++
+	}
+	return lifted, nil
+}
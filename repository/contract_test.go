@@ -0,0 +1,907 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// runUserRepositoryContract checks the behaviour every UserRepository
+// implementation must share. newRepo must return an empty repository.
+func runUserRepositoryContract(t *testing.T, newRepo func(t *testing.T) UserRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGetUser", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newTestUser("usr_1", "alice@example.com")
+		mustCreateUser(t, repo, user)
+
+		byID, err := repo.GetUserByID(ctx, "usr_1")
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if byID.Email != "alice@example.com" || byID.Name != user.Name {
+			t.Errorf("GetUserByID returned %+v", byID)
+		}
+
+		byEmail, err := repo.GetUserByEmail(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("GetUserByEmail: %v", err)
+		}
+		if byEmail.ID != "usr_1" {
+			t.Errorf("GetUserByEmail returned ID %q", byEmail.ID)
+		}
+
+		if _, err := repo.GetUserProfile(ctx, "usr_1"); err != nil {
+			t.Errorf("GetUserProfile: %v", err)
+		}
+	})
+
+	t.Run("UserNotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.GetUserByID(ctx, "missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByID: want ErrUserNotFound, got %v", err)
+		}
+		if _, err := repo.GetUserByEmail(ctx, "missing@example.com"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail: want ErrUserNotFound, got %v", err)
+		}
+		if _, err := repo.GetUserProfile(ctx, "missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserProfile: want ErrUserNotFound, got %v", err)
+		}
+		if err := repo.UpdatePassword(ctx, "missing", "hash"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("UpdatePassword: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		err := repo.CreateUser(ctx, newTestUser("usr_2", "alice@example.com"))
+		if !errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("want ErrDuplicateEmail, got %v", err)
+		}
+	})
+
+	t.Run("EmailCase", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "Alice@Example.com"))
+
+		if err := repo.CreateUser(ctx, newTestUser("usr_2", "ALICE@example.com")); !errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("CreateUser with an email differing in case: want ErrDuplicateEmail, got %v", err)
+		}
+		user, err := repo.GetUserByEmail(ctx, "alice@EXAMPLE.com")
+		if err != nil || user.ID != "usr_1" {
+			t.Fatalf("GetUserByEmail in another case = %+v, %v", user, err)
+		}
+		if user.Email != "alice@example.com" {
+			t.Errorf("stored email = %q, want it lower-cased", user.Email)
+		}
+
+		mustCreateUser(t, repo, newTestUser("usr_3", "bob@example.com"))
+		if err := repo.SetPendingEmail(ctx, "usr_3", "ALICE@EXAMPLE.COM", "hash", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := repo.ConfirmEmailChange(ctx, "usr_3"); !errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("ConfirmEmailChange to a taken email in another case: want ErrDuplicateEmail, got %v", err)
+		}
+	})
+
+	t.Run("UpdateUserAndPassword", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		if err := repo.UpdateUser(ctx, &model.User{ID: "usr_1", Name: "Alicia", PhoneNumber: 9876543210}); err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+		if err := repo.UpdatePassword(ctx, "usr_1", "new-hash"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+
+		user := mustGetUser(t, repo, "usr_1")
+		if user.Name != "Alicia" || user.PhoneNumber != 9876543210 || user.PasswordHash != "new-hash" {
+			t.Errorf("user not updated: %+v", user)
+		}
+		if user.TokenVersion != 1 {
+			t.Errorf("TokenVersion after UpdatePassword = %d, want 1", user.TokenVersion)
+		}
+		if user.Email != "alice@example.com" {
+			t.Errorf("UpdateUser must not change the email, got %q", user.Email)
+		}
+	})
+
+	t.Run("Verification", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		expiresAt := time.Now().Add(time.Hour)
+		if err := repo.StoreVerificationCode(ctx, "usr_1", "code-hash", expiresAt, SendLimit{}); err != nil {
+			t.Fatalf("StoreVerificationCode: %v", err)
+		}
+		if err := repo.IncrementVerificationAttempts(ctx, "usr_1"); err != nil {
+			t.Fatalf("IncrementVerificationAttempts: %v", err)
+		}
+
+		code, err := repo.GetVerificationCode(ctx, "usr_1")
+		if err != nil || code != "code-hash" {
+			t.Fatalf("GetVerificationCode = %q, %v", code, err)
+		}
+		user := mustGetUser(t, repo, "usr_1")
+		if user.VerificationAttempts != 1 || user.VerificationExpiresAt == nil {
+			t.Errorf("verification state not stored: %+v", user)
+		}
+
+		if err := repo.UpdateUserVerification(ctx, "usr_1", true); err != nil {
+			t.Fatalf("UpdateUserVerification: %v", err)
+		}
+		user = mustGetUser(t, repo, "usr_1")
+		if !user.IsVerified || user.VerificationCode != "" || user.VerificationAttempts != 0 {
+			t.Errorf("verification not completed: %+v", user)
+		}
+	})
+
+	t.Run("VerificationSendLimit", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		expiresAt := time.Now().Add(time.Hour)
+		cooldown := SendLimit{Cooldown: time.Hour}
+		if err := repo.StoreVerificationCode(ctx, "usr_1", "first", expiresAt, cooldown); err != nil {
+			t.Fatalf("StoreVerificationCode: %v", err)
+		}
+		if err := repo.IncrementVerificationAttempts(ctx, "usr_1"); err != nil {
+			t.Fatalf("IncrementVerificationAttempts: %v", err)
+		}
+		err := repo.StoreVerificationCode(ctx, "usr_1", "second", expiresAt, cooldown)
+		if !errors.Is(err, model.ErrTooManyAttempts) {
+			t.Fatalf("StoreVerificationCode within cooldown = %v, want ErrTooManyAttempts", err)
+		}
+		user := mustGetUser(t, repo, "usr_1")
+		if user.VerificationCode != "first" || user.VerificationAttempts != 1 || user.VerificationSends != 1 {
+			t.Errorf("throttled send changed verification state: %+v", user)
+		}
+
+		capped := SendLimit{MaxSends: 2, Window: time.Hour}
+		if err := repo.StoreVerificationCode(ctx, "usr_1", "second", expiresAt, capped); err != nil {
+			t.Fatalf("StoreVerificationCode: %v", err)
+		}
+		err = repo.StoreVerificationCode(ctx, "usr_1", "third", expiresAt, capped)
+		if !errors.Is(err, model.ErrTooManyAttempts) {
+			t.Fatalf("StoreVerificationCode over cap = %v, want ErrTooManyAttempts", err)
+		}
+		user = mustGetUser(t, repo, "usr_1")
+		if user.VerificationSends != 2 || user.VerificationAttempts != 0 || user.VerificationSentAt == nil {
+			t.Errorf("send count not stored: %+v", user)
+		}
+
+		err = repo.StoreVerificationCode(ctx, "usr_missing", "code", expiresAt, SendLimit{})
+		if !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("StoreVerificationCode for unknown user = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("EmailChange", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+
+		if err := repo.ConfirmEmailChange(ctx, "usr_1"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("ConfirmEmailChange without pending email: want ErrUserNotFound, got %v", err)
+		}
+
+		if err := repo.SetPendingEmail(ctx, "usr_1", "bob@example.com", "hash", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := repo.ConfirmEmailChange(ctx, "usr_1"); !errors.Is(err, model.ErrDuplicateEmail) {
+			t.Errorf("ConfirmEmailChange to taken email: want ErrDuplicateEmail, got %v", err)
+		}
+
+		if err := repo.SetPendingEmail(ctx, "usr_1", "alice@new.example.com", "hash", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := repo.IncrementEmailChangeAttempts(ctx, "usr_1"); err != nil {
+			t.Fatalf("IncrementEmailChangeAttempts: %v", err)
+		}
+		if err := repo.ConfirmEmailChange(ctx, "usr_1"); err != nil {
+			t.Fatalf("ConfirmEmailChange: %v", err)
+		}
+
+		user := mustGetUser(t, repo, "usr_1")
+		if user.Email != "alice@new.example.com" || user.PendingEmail != "" || user.EmailChangeAttempts != 0 {
+			t.Errorf("email not changed: %+v", user)
+		}
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		if roles, err := repo.GetUserRoles(ctx, "usr_1"); err != nil || len(roles) != 0 {
+			t.Fatalf("GetUserRoles on new user = %v, %v; want none", roles, err)
+		}
+
+		for _, role := range []model.Role{model.RoleModerator, model.RoleAdmin, model.RoleModerator} {
+			if err := repo.GrantRole(ctx, &model.UserRole{UserID: "usr_1", Role: role, GrantedBy: "usr_admin"}); err != nil {
+				t.Fatalf("GrantRole(%s): %v", role, err)
+			}
+		}
+		roles, err := repo.GetUserRoles(ctx, "usr_1")
+		if err != nil {
+			t.Fatalf("GetUserRoles: %v", err)
+		}
+		if !reflect.DeepEqual(roles, []model.Role{model.RoleAdmin, model.RoleModerator}) {
+			t.Errorf("GetUserRoles = %v, want [admin moderator]", roles)
+		}
+
+		if err := repo.RevokeRole(ctx, "usr_1", model.RoleAdmin); err != nil {
+			t.Fatalf("RevokeRole: %v", err)
+		}
+		if err := repo.RevokeRole(ctx, "usr_1", model.RoleSupport); err != nil {
+			t.Errorf("RevokeRole of a role not held: %v", err)
+		}
+		if roles, _ := repo.GetUserRoles(ctx, "usr_1"); !reflect.DeepEqual(roles, []model.Role{model.RoleModerator}) {
+			t.Errorf("GetUserRoles after revoke = %v, want [moderator]", roles)
+		}
+
+		if err := repo.GrantRole(ctx, &model.UserRole{UserID: "missing", Role: model.RoleAdmin}); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GrantRole for missing user: want ErrUserNotFound, got %v", err)
+		}
+		if err := repo.RevokeRole(ctx, "missing", model.RoleAdmin); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("RevokeRole for missing user: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Bans", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		if banned, err := repo.CheckBan(ctx, "usr_1"); err != nil || banned {
+			t.Fatalf("CheckBan before ban = %v, %v", banned, err)
+		}
+		if err := repo.BanUser(ctx, newTestBan("ban_1", "usr_1", nil)); err != nil {
+			t.Fatalf("BanUser: %v", err)
+		}
+		if err := repo.BanUser(ctx, newTestBan("ban_2", "usr_1", nil)); !errors.Is(err, model.ErrUserAlreadyBanned) {
+			t.Errorf("second BanUser: want ErrUserAlreadyBanned, got %v", err)
+		}
+		if banned, err := repo.CheckBan(ctx, "usr_1"); err != nil || !banned {
+			t.Errorf("CheckBan after ban = %v, %v", banned, err)
+		}
+		if err := repo.UnBanUser(ctx, "usr_1", "usr_admin"); err != nil {
+			t.Fatalf("UnBanUser: %v", err)
+		}
+		if banned, err := repo.CheckBan(ctx, "usr_1"); err != nil || banned {
+			t.Errorf("CheckBan after unban = %v, %v", banned, err)
+		}
+		if err := repo.UnBanUser(ctx, "usr_1", "usr_admin"); err != nil {
+			t.Errorf("UnBanUser of an unbanned user: %v", err)
+		}
+
+		if err := repo.BanUser(ctx, newTestBan("ban_3", "missing", nil)); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("BanUser missing: want ErrUserNotFound, got %v", err)
+		}
+		if _, err := repo.CheckBan(ctx, "missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("CheckBan missing: want ErrUserNotFound, got %v", err)
+		}
+		if _, err := repo.GetBanHistory(ctx, "missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetBanHistory missing: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("BanHistory", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		first := newTestBan("ban_1", "usr_1", nil)
+		first.CreatedAt = time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := repo.BanUser(ctx, first); err != nil {
+			t.Fatalf("BanUser: %v", err)
+		}
+		if err := repo.UnBanUser(ctx, "usr_1", "usr_admin"); err != nil {
+			t.Fatalf("UnBanUser: %v", err)
+		}
+		if err := repo.BanUser(ctx, newTestBan("ban_2", "usr_1", nil)); err != nil {
+			t.Fatalf("second BanUser: %v", err)
+		}
+
+		history, err := repo.GetBanHistory(ctx, "usr_1")
+		if err != nil {
+			t.Fatalf("GetBanHistory: %v", err)
+		}
+		if len(history) != 2 || history[0].ID != "ban_2" || history[1].ID != "ban_1" {
+			t.Fatalf("GetBanHistory = %+v, want ban_2 then ban_1", history)
+		}
+		if history[1].LiftedAt == nil || history[1].LiftedBy != "usr_admin" {
+			t.Errorf("lifted ban = %+v, want lifted by usr_admin", history[1])
+		}
+		if history[0].LiftedAt != nil || history[0].Reason != "spam" || history[0].BannedBy != "usr_moderator" {
+			t.Errorf("active ban = %+v", history[0])
+		}
+	})
+
+	t.Run("ExpiringBans", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_3", "carol@example.com"))
+
+		expired := time.Now().Add(-time.Minute).Truncate(time.Second)
+		future := time.Now().Add(time.Hour).Truncate(time.Second)
+		for _, ban := range []*model.UserBan{
+			newTestBan("ban_1", "usr_1", &expired),
+			newTestBan("ban_2", "usr_2", &expired),
+			newTestBan("ban_3", "usr_3", &future),
+		} {
+			if err := repo.BanUser(ctx, ban); err != nil {
+				t.Fatalf("BanUser(%s): %v", ban.UserID, err)
+			}
+		}
+
+		// Checking an expired ban lifts it
+		if banned, err := repo.CheckBan(ctx, "usr_1"); err != nil || banned {
+			t.Errorf("CheckBan after expiry = %v, %v", banned, err)
+		}
+		history, _ := repo.GetBanHistory(ctx, "usr_1")
+		if len(history) != 1 || history[0].LiftedAt == nil || !history[0].LiftedAt.Equal(expired) || history[0].LiftedBy != "" {
+			t.Errorf("lapsed ban = %+v, want lifted at its expiry by nobody", history)
+		}
+
+		// The sweep lifts the remaining expired ban and leaves the active one
+		lifted, err := repo.LiftExpiredBans(ctx, time.Now())
+		if err != nil || lifted != 1 {
+			t.Errorf("LiftExpiredBans = %d, %v; want 1", lifted, err)
+		}
+		if user, _ := repo.GetUserByID(ctx, "usr_2"); user.IsBanned {
+			t.Error("usr_2 still banned after the sweep")
+		}
+		if banned, err := repo.CheckBan(ctx, "usr_3"); err != nil || !banned {
+			t.Errorf("CheckBan before expiry = %v, %v", banned, err)
+		}
+
+		// A lapsed ban does not block a new one
+		if err := repo.BanUser(ctx, newTestBan("ban_4", "usr_2", nil)); err != nil {
+			t.Errorf("BanUser after expiry: %v", err)
+		}
+	})
+
+	t.Run("Reputation", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		adjust := func(id, key string, delta int32) (*ReputationAdjustment, error) {
+			return repo.AdjustReputation(ctx, &model.ReputationEvent{
+				ID: id, UserID: "usr_1", Delta: delta, Reason: "order completed",
+				SourceService: "order", ReferenceID: "ord_1", IdempotencyKey: key,
+			})
+		}
+		if result, err := adjust("rep_1", "key_1", 10); err != nil || result.Reputation != 10 || result.Replayed {
+			t.Fatalf("first adjustment = %+v, %v", result, err)
+		}
+		if result, err := adjust("rep_2", "key_2", -3); err != nil || result.Reputation != 7 {
+			t.Fatalf("second adjustment = %+v, %v", result, err)
+		}
+
+		replay, err := adjust("rep_3", "key_1", 10)
+		if err != nil || !replay.Replayed || replay.Event.ID != "rep_1" || replay.Reputation != 7 {
+			t.Errorf("replayed adjustment = %+v, %v; want rep_1 replayed at 7", replay, err)
+		}
+		if _, err := adjust("rep_4", "key_1", 5); !errors.Is(err, model.ErrIdempotencyReused) {
+			t.Errorf("reused key: want ErrIdempotencyReused, got %v", err)
+		}
+
+		// The same key from another service is a different event
+		other := &model.ReputationEvent{ID: "rep_5", UserID: "usr_1", Delta: 1, SourceService: "review", IdempotencyKey: "key_1"}
+		if result, err := repo.AdjustReputation(ctx, other); err != nil || result.Reputation != 8 {
+			t.Errorf("adjustment from another service = %+v, %v", result, err)
+		}
+		if user, _ := repo.GetUserByID(ctx, "usr_1"); user.Reputation != 8 {
+			t.Errorf("cached reputation = %d, want 8", user.Reputation)
+		}
+
+		missing := &model.ReputationEvent{ID: "rep_6", UserID: "missing", Delta: 1, SourceService: "order", IdempotencyKey: "key_6"}
+		if _, err := repo.AdjustReputation(ctx, missing); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("adjustment for missing user: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Leaderboard", func(t *testing.T) {
+		repo := newRepo(t)
+		for i, reputation := range []int32{50, 80, 50, 10, 90} {
+			user := newTestUser(fmt.Sprintf("usr_%d", i), fmt.Sprintf("user%d@example.com", i))
+			user.Reputation = reputation
+			mustCreateUser(t, repo, user)
+		}
+		if err := repo.BanUser(ctx, newTestBan("ban_1", "usr_4", nil)); err != nil {
+			t.Fatalf("BanUser: %v", err)
+		}
+
+		entries, err := repo.GetLeaderboard(ctx, 3)
+		if err != nil {
+			t.Fatalf("GetLeaderboard: %v", err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, fmt.Sprintf("%d:%s", entry.Rank, entry.User.ID))
+		}
+		if want := []string{"1:usr_1", "2:usr_0", "2:usr_2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetLeaderboard = %v, want %v", got, want)
+		}
+
+		for userID, want := range map[string]int64{"usr_1": 1, "usr_2": 2, "usr_3": 4, "usr_4": 0} {
+			if rank, err := repo.GetReputationRank(ctx, userID); err != nil || rank != want {
+				t.Errorf("GetReputationRank(%s) = %d, %v; want %d", userID, rank, err, want)
+			}
+		}
+		if _, err := repo.GetReputationRank(ctx, "missing"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetReputationRank missing: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		for i, name := range []string{"dave", "carol", "bob", "alice", "erin"} {
+			user := newTestUser(fmt.Sprintf("usr_%d", i), name+"@example.com")
+			user.Name = name
+			user.Reputation = int32(i * 10)
+			user.IsVerified = i%2 == 0
+			user.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			mustCreateUser(t, repo, user)
+		}
+
+		// Walk all pages sorted by name
+		var names []string
+		afterID := ""
+		for {
+			page, err := repo.ListUsers(ctx, UserListOptions{SortBy: SortByName, Limit: 2, AfterID: afterID})
+			if err != nil {
+				t.Fatalf("ListUsers: %v", err)
+			}
+			if page.TotalCount != 5 {
+				t.Errorf("TotalCount = %d, want 5", page.TotalCount)
+			}
+			for _, user := range page.Users {
+				names = append(names, user.Name)
+			}
+			if page.NextAfterID == "" {
+				break
+			}
+			afterID = page.NextAfterID
+		}
+		if fmt.Sprint(names) != "[alice bob carol dave erin]" {
+			t.Errorf("paged names = %v", names)
+		}
+
+		page, err := repo.ListUsers(ctx, UserListOptions{SortBy: SortByReputation, Descending: true, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListUsers by reputation: %v", err)
+		}
+		if len(page.Users) != 5 || page.Users[0].Name != "erin" || page.Users[4].Name != "dave" {
+			t.Errorf("descending reputation order wrong: %v", userNames(page.Users))
+		}
+
+		verified := true
+		minRep := int32(10)
+		page, err = repo.ListUsers(ctx, UserListOptions{
+			Filter: UserFilter{IsVerified: &verified, MinReputation: &minRep},
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatalf("ListUsers filtered: %v", err)
+		}
+		if page.TotalCount != 2 || fmt.Sprint(userNames(page.Users)) != "[bob erin]" {
+			t.Errorf("filtered users = %v (total %d)", userNames(page.Users), page.TotalCount)
+		}
+
+		page, err = repo.ListUsers(ctx, UserListOptions{Filter: UserFilter{Email: "CAR"}, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListUsers by email: %v", err)
+		}
+		if fmt.Sprint(userNames(page.Users)) != "[carol]" {
+			t.Errorf("email substring match = %v", userNames(page.Users))
+		}
+
+		if _, err := repo.ListUsers(ctx, UserListOptions{Limit: 10, AfterID: "missing"}); !errors.Is(err, model.ErrInvalidArgument) {
+			t.Errorf("unknown cursor: want ErrInvalidArgument, got %v", err)
+		}
+	})
+
+	t.Run("ScanUsers", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 0; i < 5; i++ {
+			mustCreateUser(t, repo, newTestUser(fmt.Sprintf("usr_%d", i), fmt.Sprintf("user%d@example.com", i)))
+		}
+
+		var ids []string
+		afterID := ""
+		for {
+			users, err := repo.ScanUsers(ctx, afterID, 2)
+			if err != nil {
+				t.Fatalf("ScanUsers: %v", err)
+			}
+			for _, user := range users {
+				ids = append(ids, user.ID)
+			}
+			if len(users) < 2 {
+				break
+			}
+			afterID = users[len(users)-1].ID
+		}
+		if fmt.Sprint(ids) != "[usr_0 usr_1 usr_2 usr_3 usr_4]" {
+			t.Errorf("scanned IDs = %v", ids)
+		}
+	})
+
+	t.Run("Addresses", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+
+		addressID, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{
+			StreetName: "MG Road",
+			Locality:   "Ernakulam",
+			State:      "Kerala",
+			Pincode:    "682016",
+		}, 0)
+		if err != nil {
+			t.Fatalf("AddAddress: %v", err)
+		}
+
+		addresses, err := repo.GetAddresses(ctx, "usr_1")
+		if err != nil || len(addresses) != 1 || addresses[0].ID != addressID {
+			t.Fatalf("GetAddresses = %v, %v", addresses, err)
+		}
+
+		lat, lon := 9.9816, 76.278
+		edited := &model.UserAddress{StreetName: "Marine Drive", Locality: "Ernakulam", State: "Kerala", Pincode: "682031", Latitude: &lat, Longitude: &lon}
+		if err := repo.EditAddress(ctx, "usr_2", addressID, edited); !errors.Is(err, model.ErrAddressNotFound) {
+			t.Errorf("EditAddress by other user: want ErrAddressNotFound, got %v", err)
+		}
+		if err := repo.EditAddress(ctx, "usr_1", addressID, edited); err != nil {
+			t.Fatalf("EditAddress: %v", err)
+		}
+		// An edit that changes nothing still finds the address
+		if err := repo.EditAddress(ctx, "usr_1", addressID, edited); err != nil {
+			t.Fatalf("EditAddress with unchanged values: %v", err)
+		}
+		addresses, _ = repo.GetAddresses(ctx, "usr_1")
+		if addresses[0].StreetName != "Marine Drive" || addresses[0].Pincode != "682031" {
+			t.Errorf("address not edited: %+v", addresses[0])
+		}
+		if addresses[0].Latitude == nil || *addresses[0].Latitude != lat || addresses[0].Longitude == nil || *addresses[0].Longitude != lon {
+			t.Errorf("coordinates not edited: %v, %v", addresses[0].Latitude, addresses[0].Longitude)
+		}
+
+		// Coordinates are cleared when the new address cannot be located
+		if err := repo.EditAddress(ctx, "usr_1", addressID, &model.UserAddress{StreetName: "Unknown"}); err != nil {
+			t.Fatalf("EditAddress without coordinates: %v", err)
+		}
+		if addresses, _ = repo.GetAddresses(ctx, "usr_1"); addresses[0].Latitude != nil || addresses[0].Longitude != nil {
+			t.Errorf("coordinates not cleared: %v, %v", addresses[0].Latitude, addresses[0].Longitude)
+		}
+
+		if err := repo.DeleteAddress(ctx, "usr_2", addressID); !errors.Is(err, model.ErrAddressNotFound) {
+			t.Errorf("DeleteAddress by other user: want ErrAddressNotFound, got %v", err)
+		}
+		if err := repo.DeleteAddress(ctx, "usr_1", addressID); err != nil {
+			t.Fatalf("DeleteAddress: %v", err)
+		}
+		if addresses, _ := repo.GetAddresses(ctx, "usr_1"); len(addresses) != 0 {
+			t.Errorf("address not deleted: %v", addresses)
+		}
+	})
+
+	t.Run("DefaultAddress", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+
+		var ids []string
+		for _, street := range []string{"MG Road", "Marine Drive", "Broadway"} {
+			id, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{StreetName: street, Pincode: "682016"}, 0)
+			if err != nil {
+				t.Fatalf("AddAddress(%s): %v", street, err)
+			}
+			ids = append(ids, id)
+		}
+		addressOrder := func() []string {
+			addresses, err := repo.GetAddresses(ctx, "usr_1")
+			if err != nil {
+				t.Fatalf("GetAddresses: %v", err)
+			}
+			var order []string
+			for _, address := range addresses {
+				id := address.ID
+				if address.IsDefault {
+					id += "*"
+				}
+				order = append(order, id)
+			}
+			return order
+		}
+
+		// The first address is the default and comes first
+		if got, want := addressOrder(), []string{ids[0] + "*", ids[1], ids[2]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("addresses = %v, want %v", got, want)
+		}
+		if err := repo.SetDefaultAddress(ctx, "usr_1", ids[2]); err != nil {
+			t.Fatalf("SetDefaultAddress: %v", err)
+		}
+		if got, want := addressOrder(), []string{ids[2] + "*", ids[0], ids[1]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("addresses after SetDefaultAddress = %v, want %v", got, want)
+		}
+		if err := repo.SetDefaultAddress(ctx, "usr_2", ids[1]); !errors.Is(err, model.ErrAddressNotFound) {
+			t.Errorf("SetDefaultAddress by other user: want ErrAddressNotFound, got %v", err)
+		}
+
+		// Deleting the default promotes the oldest remaining address
+		if err := repo.DeleteAddress(ctx, "usr_1", ids[2]); err != nil {
+			t.Fatalf("DeleteAddress: %v", err)
+		}
+		if got, want := addressOrder(), []string{ids[0] + "*", ids[1]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("addresses after deleting the default = %v, want %v", got, want)
+		}
+
+		if _, err := repo.AddAddress(ctx, "missing", &model.UserAddress{StreetName: "Nowhere"}, 0); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("AddAddress for missing user: want ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("AddressLimit", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+
+		seen := make(map[string]bool)
+		for _, street := range []string{"MG Road", "Marine Drive"} {
+			id, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{StreetName: street}, 2)
+			if err != nil {
+				t.Fatalf("AddAddress(%s): %v", street, err)
+			}
+			if !strings.HasPrefix(id, "addr_") || seen[id] {
+				t.Errorf("address ID %q is not a new addr_ ID", id)
+			}
+			seen[id] = true
+		}
+
+		if _, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{StreetName: "Broadway"}, 2); !errors.Is(err, model.ErrAddressLimit) {
+			t.Errorf("AddAddress over the limit: want ErrAddressLimit, got %v", err)
+		}
+		if addresses, _ := repo.GetAddresses(ctx, "usr_1"); len(addresses) != 2 {
+			t.Errorf("%d addresses stored, want 2", len(addresses))
+		}
+		// The limit is per user, and 0 lifts it
+		if _, err := repo.AddAddress(ctx, "usr_2", &model.UserAddress{StreetName: "Broadway"}, 2); err != nil {
+			t.Errorf("AddAddress for another user: %v", err)
+		}
+		if _, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{StreetName: "Broadway"}, 0); err != nil {
+			t.Errorf("AddAddress without a limit: %v", err)
+		}
+	})
+
+	t.Run("AddressDetails", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		mustCreateUser(t, repo, newTestUser("usr_2", "bob@example.com"))
+		id, err := repo.AddAddress(ctx, "usr_1", &model.UserAddress{StreetName: "MG Road", Label: model.AddressLabelOther}, 0)
+		if err != nil {
+			t.Fatalf("AddAddress: %v", err)
+		}
+
+		details := &model.UserAddress{
+			Label:                model.AddressLabelHome,
+			Landmark:             "Opposite the metro station",
+			FlatNumber:           "4B, 2nd floor",
+			DeliveryInstructions: "Ring twice",
+		}
+		if err := repo.UpdateAddressDetails(ctx, "usr_2", id, details); !errors.Is(err, model.ErrAddressNotFound) {
+			t.Errorf("UpdateAddressDetails by other user: want ErrAddressNotFound, got %v", err)
+		}
+		if err := repo.UpdateAddressDetails(ctx, "usr_1", id, details); err != nil {
+			t.Fatalf("UpdateAddressDetails: %v", err)
+		}
+		if err := repo.UpdateAddressDetails(ctx, "usr_1", id, details); err != nil {
+			t.Fatalf("UpdateAddressDetails with unchanged values: %v", err)
+		}
+
+		// Editing the street keeps the details
+		if err := repo.EditAddress(ctx, "usr_1", id, &model.UserAddress{StreetName: "Marine Drive"}); err != nil {
+			t.Fatalf("EditAddress: %v", err)
+		}
+		addresses, _ := repo.GetAddresses(ctx, "usr_1")
+		got := addresses[0]
+		if got.StreetName != "Marine Drive" || got.Label != model.AddressLabelHome || got.Landmark != details.Landmark ||
+			got.FlatNumber != details.FlatNumber || got.DeliveryInstructions != details.DeliveryInstructions {
+			t.Errorf("address after updates = %+v", got)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		first := newTestSession("ses_1", "ses_1", "hash-1")
+		if err := repo.CreateSession(ctx, first); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if _, err := repo.GetSessionByTokenHash(ctx, "unknown"); !errors.Is(err, model.ErrSessionNotFound) {
+			t.Errorf("GetSessionByTokenHash unknown: want ErrSessionNotFound, got %v", err)
+		}
+
+		second := newTestSession("ses_2", "ses_1", "hash-2")
+		if err := repo.RotateSession(ctx, "ses_1", second); err != nil {
+			t.Fatalf("RotateSession: %v", err)
+		}
+		rotated, err := repo.GetSessionByTokenHash(ctx, "hash-1")
+		if err != nil || rotated.RotatedAt == nil {
+			t.Fatalf("rotated session = %+v, %v", rotated, err)
+		}
+
+		third := newTestSession("ses_3", "ses_1", "hash-3")
+		if err := repo.RotateSession(ctx, "ses_1", third); !errors.Is(err, model.ErrRefreshTokenReused) {
+			t.Errorf("rotating twice: want ErrRefreshTokenReused, got %v", err)
+		}
+
+		if active, err := repo.IsSessionFamilyActive(ctx, "ses_1"); err != nil || !active {
+			t.Errorf("IsSessionFamilyActive before revoke = %v, %v", active, err)
+		}
+		if err := repo.RevokeSessionFamily(ctx, "ses_1"); err != nil {
+			t.Fatalf("RevokeSessionFamily: %v", err)
+		}
+		if active, err := repo.IsSessionFamilyActive(ctx, "ses_1"); err != nil || active {
+			t.Errorf("IsSessionFamilyActive after revoke = %v, %v", active, err)
+		}
+
+		other := newTestSession("ses_4", "ses_4", "hash-4")
+		if err := repo.CreateSession(ctx, other); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if err := repo.RevokeUserSessions(ctx, "usr_1"); err != nil {
+			t.Fatalf("RevokeUserSessions: %v", err)
+		}
+		revoked, err := repo.GetSessionByTokenHash(ctx, "hash-4")
+		if err != nil || revoked.RevokedAt == nil {
+			t.Errorf("session not revoked: %+v, %v", revoked, err)
+		}
+		if user := mustGetUser(t, repo, "usr_1"); user.TokenVersion != 1 {
+			t.Errorf("TokenVersion = %d after RevokeUserSessions, want 1", user.TokenVersion)
+		}
+	})
+
+	t.Run("PasswordReset", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+		if err := repo.CreateSession(ctx, newTestSession("ses_1", "ses_1", "hash-1")); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+
+		expiresAt := time.Now().Add(time.Hour)
+		for i, hash := range []string{"reset-1", "reset-2"} {
+			token := &model.PasswordResetToken{ID: fmt.Sprintf("prt_%d", i), UserID: "usr_1", TokenHash: hash, ExpiresAt: expiresAt}
+			if err := repo.CreatePasswordResetToken(ctx, token, SendLimit{}); err != nil {
+				t.Fatalf("CreatePasswordResetToken: %v", err)
+			}
+		}
+
+		if _, err := repo.ResetPassword(ctx, "reset-1", "new-hash"); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("superseded token: want ErrInvalidToken, got %v", err)
+		}
+
+		userID, err := repo.ResetPassword(ctx, "reset-2", "new-hash")
+		if err != nil || userID != "usr_1" {
+			t.Fatalf("ResetPassword = %q, %v", userID, err)
+		}
+		if user := mustGetUser(t, repo, "usr_1"); user.PasswordHash != "new-hash" || user.TokenVersion != 1 {
+			t.Errorf("password not reset: %q, token version %d", user.PasswordHash, user.TokenVersion)
+		}
+		if active, _ := repo.IsSessionFamilyActive(ctx, "ses_1"); active {
+			t.Error("sessions must be revoked after a password reset")
+		}
+
+		if _, err := repo.ResetPassword(ctx, "reset-2", "other-hash"); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("reused token: want ErrInvalidToken, got %v", err)
+		}
+
+		expired := &model.PasswordResetToken{ID: "prt_3", UserID: "usr_1", TokenHash: "reset-3", ExpiresAt: time.Now().Add(-time.Minute)}
+		if err := repo.CreatePasswordResetToken(ctx, expired, SendLimit{}); err != nil {
+			t.Fatalf("CreatePasswordResetToken: %v", err)
+		}
+		if _, err := repo.ResetPassword(ctx, "reset-3", "other-hash"); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("expired token: want ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("PasswordResetSendLimit", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateUser(t, repo, newTestUser("usr_1", "alice@example.com"))
+
+		expiresAt := time.Now().Add(time.Hour)
+		limit := SendLimit{Cooldown: time.Hour}
+		first := &model.PasswordResetToken{ID: "prt_1", UserID: "usr_1", TokenHash: "reset-1", ExpiresAt: expiresAt}
+		if err := repo.CreatePasswordResetToken(ctx, first, limit); err != nil {
+			t.Fatalf("CreatePasswordResetToken: %v", err)
+		}
+		second := &model.PasswordResetToken{ID: "prt_2", UserID: "usr_1", TokenHash: "reset-2", ExpiresAt: expiresAt}
+		err := repo.CreatePasswordResetToken(ctx, second, limit)
+		if !errors.Is(err, model.ErrTooManyAttempts) {
+			t.Fatalf("CreatePasswordResetToken within cooldown = %v, want ErrTooManyAttempts", err)
+		}
+
+		// The throttled request leaves the earlier token usable
+		if _, err := repo.ResetPassword(ctx, "reset-2", "new-hash"); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("throttled token: want ErrInvalidToken, got %v", err)
+		}
+		if _, err := repo.ResetPassword(ctx, "reset-1", "new-hash"); err != nil {
+			t.Errorf("ResetPassword with the earlier token: %v", err)
+		}
+		if user := mustGetUser(t, repo, "usr_1"); user.PasswordResetSends != 1 || user.PasswordResetSentAt == nil {
+			t.Errorf("send count not stored: %+v", user)
+		}
+
+		unknown := &model.PasswordResetToken{ID: "prt_3", UserID: "usr_missing", TokenHash: "reset-3", ExpiresAt: expiresAt}
+		if err := repo.CreatePasswordResetToken(ctx, unknown, SendLimit{}); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("CreatePasswordResetToken for unknown user = %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+func newTestUser(id, email string) *model.User {
+	return &model.User{
+		ID:           id,
+		Email:        email,
+		PasswordHash: "hash",
+		Name:         "Test User",
+		PhoneNumber:  9123456789,
+	}
+}
+
+func newTestBan(id, userID string, expiresAt *time.Time) *model.UserBan {
+	return &model.UserBan{
+		ID:        id,
+		UserID:    userID,
+		Reason:    "spam",
+		BannedBy:  "usr_moderator",
+		ExpiresAt: expiresAt,
+	}
+}
+
+func newTestSession(id, familyID, tokenHash string) *model.Session {
+	return &model.Session{
+		ID:               id,
+		UserID:           "usr_1",
+		FamilyID:         familyID,
+		RefreshTokenHash: tokenHash,
+		DeviceInfo:       "test",
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+}
+
+func mustCreateUser(t *testing.T, repo UserRepository, user *model.User) {
+	t.Helper()
+	if err := repo.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", user.ID, err)
+	}
+}
+
+func mustGetUser(t *testing.T, repo UserRepository, id string) *model.User {
+	t.Helper()
+	user, err := repo.GetUserByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserByID(%s): %v", id, err)
+	}
+	return user
+}
+
+func userNames(users []*model.User) []string {
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names
+}
@@ -0,0 +1,839 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+// memoryRepository is a thread-safe, in-memory UserRepository for tests and
+// local development. It mirrors the semantics of the GORM implementation and
+// hands out copies so callers can never mutate stored records.
+type memoryRepository struct {
+	mu             sync.RWMutex
+	users          map[string]*model.User
+	addresses      map[string]*model.UserAddress
+	addressOrder   []string
+	sessions       map[string]*model.Session
+	passwordResets map[string]*model.PasswordResetToken
+	roles          map[string]map[model.Role]model.UserRole
+	bans           []*model.UserBan
+	// reputationEvents is keyed by source service and idempotency key
+	reputationEvents map[reputationKey]*model.ReputationEvent
+}
+
+type reputationKey struct {
+	sourceService  string
+	idempotencyKey string
+}
+
+// NewMemoryRepository returns an empty in-memory UserRepository
+func NewMemoryRepository() UserRepository {
+	return &memoryRepository{
+		users:          make(map[string]*model.User),
+		addresses:      make(map[string]*model.UserAddress),
+		sessions:       make(map[string]*model.Session),
+		passwordResets: make(map[string]*model.PasswordResetToken),
+		roles:          make(map[string]map[model.Role]model.UserRole),
+
+		reputationEvents: make(map[reputationKey]*model.ReputationEvent),
+	}
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("failed to create user: duplicate id %q", user.ID)
+	}
+	user.Email = normalizeEmail(user.Email)
+	if r.findUserByEmail(user.Email) != nil {
+		return model.ErrDuplicateEmail
+	}
+
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user := r.findUserByEmail(email)
+	if user == nil {
+		return nil, model.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, model.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryRepository) GetUserProfile(ctx context.Context, userID string) (*model.User, error) {
+	return r.GetUserByID(ctx, userID)
+}
+
+func (r *memoryRepository) UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		user.IsVerified = isVerified
+		if isVerified {
+			user.VerificationCode = ""
+			user.VerificationExpiresAt = nil
+			user.VerificationAttempts = 0
+		}
+		return nil
+	})
+}
+
+func (r *memoryRepository) UpdateUser(ctx context.Context, user *model.User) error {
+	return r.updateUser(user.ID, func(stored *model.User) error {
+		stored.Name = user.Name
+		stored.PhoneNumber = user.PhoneNumber
+		return nil
+	})
+}
+
+func (r *memoryRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		user.PasswordHash = passwordHash
+		user.TokenVersion++
+		return nil
+	})
+}
+
+func (r *memoryRepository) SetPendingEmail(ctx context.Context, userID, email, codeHash string, expiresAt time.Time) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		user.PendingEmail = normalizeEmail(email)
+		user.EmailChangeCode = codeHash
+		user.EmailChangeExpiresAt = &expiresAt
+		user.EmailChangeAttempts = 0
+		return nil
+	})
+}
+
+func (r *memoryRepository) IncrementEmailChangeAttempts(ctx context.Context, userID string) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		user.EmailChangeAttempts++
+		return nil
+	})
+}
+
+func (r *memoryRepository) ConfirmEmailChange(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok || user.PendingEmail == "" {
+		return model.ErrUserNotFound
+	}
+	if other := r.findUserByEmail(user.PendingEmail); other != nil && other.ID != userID {
+		return model.ErrDuplicateEmail
+	}
+
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailChangeCode = ""
+	user.EmailChangeExpiresAt = nil
+	user.EmailChangeAttempts = 0
+	return nil
+}
+
+func (r *memoryRepository) StoreVerificationCode(ctx context.Context, userID, codeHash string, expiresAt time.Time, limit SendLimit) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		now := time.Now()
+		sends, ok := limit.next(now, user.VerificationSentAt, user.VerificationSends)
+		if !ok {
+			return fmt.Errorf("%w: verification code sent too often", model.ErrTooManyAttempts)
+		}
+		user.VerificationCode = codeHash
+		user.VerificationExpiresAt = &expiresAt
+		user.VerificationAttempts = 0
+		user.VerificationSentAt = &now
+		user.VerificationSends = sends
+		return nil
+	})
+}
+
+func (r *memoryRepository) GetVerificationCode(ctx context.Context, userID string) (string, error) {
+	user, err := r.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return user.VerificationCode, nil
+}
+
+func (r *memoryRepository) IncrementVerificationAttempts(ctx context.Context, userID string) error {
+	return r.updateUser(userID, func(user *model.User) error {
+		user.VerificationAttempts++
+		return nil
+	})
+}
+
+func (r *memoryRepository) CheckBan(ctx context.Context, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return true, model.ErrUserNotFound
+	}
+	r.liftExpiredBans(time.Now(), userID)
+	return user.IsBanned, nil
+}
+
+func (r *memoryRepository) BanUser(ctx context.Context, ban *model.UserBan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[ban.UserID]
+	if !ok {
+		return model.ErrUserNotFound
+	}
+	r.liftExpiredBans(time.Now(), ban.UserID)
+	if user.IsBanned {
+		return model.ErrUserAlreadyBanned
+	}
+
+	if ban.CreatedAt.IsZero() {
+		ban.CreatedAt = time.Now()
+	}
+	stored := *ban
+	r.bans = append(r.bans, &stored)
+	user.IsBanned = true
+	return nil
+}
+
+func (r *memoryRepository) UnBanUser(ctx context.Context, userID, liftedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return model.ErrUserNotFound
+	}
+	now := time.Now()
+	r.liftExpiredBans(now, userID)
+	for _, ban := range r.bans {
+		if ban.UserID == userID && ban.LiftedAt == nil {
+			liftedAt := now
+			ban.LiftedAt = &liftedAt
+			ban.LiftedBy = liftedBy
+		}
+	}
+	user.IsBanned = false
+	return nil
+}
+
+func (r *memoryRepository) GetBanHistory(ctx context.Context, userID string) ([]*model.UserBan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return nil, model.ErrUserNotFound
+	}
+	var bans []*model.UserBan
+	for i := len(r.bans) - 1; i >= 0; i-- {
+		if r.bans[i].UserID == userID {
+			copied := *r.bans[i]
+			bans = append(bans, &copied)
+		}
+	}
+	sort.SliceStable(bans, func(i, j int) bool { return bans[i].CreatedAt.After(bans[j].CreatedAt) })
+	return bans, nil
+}
+
+func (r *memoryRepository) LiftExpiredBans(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.liftExpiredBans(now, ""), nil
+}
+
+// liftExpiredBans must be called with the write lock held
+func (r *memoryRepository) liftExpiredBans(now time.Time, userID string) int {
+	lifted := 0
+	for _, ban := range r.bans {
+		if userID != "" && ban.UserID != userID {
+			continue
+		}
+		if ban.LiftedAt != nil || ban.ExpiresAt == nil || ban.ExpiresAt.After(now) {
+			continue
+		}
+		liftedAt := *ban.ExpiresAt
+		ban.LiftedAt = &liftedAt
+		if user, ok := r.users[ban.UserID]; ok {
+			user.IsBanned = false
+		}
+		lifted++
+	}
+	return lifted
+}
+
+func (r *memoryRepository) AdjustReputation(ctx context.Context, event *model.ReputationEvent) (*ReputationAdjustment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[event.UserID]
+	if !ok {
+		return nil, model.ErrUserNotFound
+	}
+
+	key := reputationKey{event.SourceService, event.IdempotencyKey}
+	if existing, ok := r.reputationEvents[key]; ok {
+		if existing.UserID != event.UserID || existing.Delta != event.Delta {
+			return nil, model.ErrIdempotencyReused
+		}
+		copied := *existing
+		return &ReputationAdjustment{Event: &copied, Reputation: user.Reputation, Replayed: true}, nil
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	stored := *event
+	r.reputationEvents[key] = &stored
+
+	var sum int32
+	for _, e := range r.reputationEvents {
+		if e.UserID == event.UserID {
+			sum += e.Delta
+		}
+	}
+	user.Reputation = sum
+	return &ReputationAdjustment{Event: event, Reputation: sum}, nil
+}
+
+func (r *memoryRepository) GetLeaderboard(ctx context.Context, limit int) ([]*LeaderboardEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*model.User
+	for _, user := range r.users {
+		if !user.IsBanned {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Reputation != users[j].Reputation {
+			return users[i].Reputation > users[j].Reputation
+		}
+		return users[i].ID < users[j].ID
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return rankUsers(users), nil
+}
+
+func (r *memoryRepository) GetReputationRank(ctx context.Context, userID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return 0, model.ErrUserNotFound
+	}
+	if user.IsBanned {
+		return 0, nil
+	}
+	var ahead int64
+	for _, other := range r.users {
+		if !other.IsBanned && other.Reputation > user.Reputation {
+			ahead++
+		}
+	}
+	return ahead + 1, nil
+}
+
+func (r *memoryRepository) ListUsers(ctx context.Context, opts UserListOptions) (*UserPage, error) {
+	sortBy := opts.SortBy
+	switch sortBy {
+	case SortByCreatedAt, SortByName, SortByEmail, SortByReputation:
+	case "":
+		sortBy = SortByCreatedAt
+	default:
+		return nil, fmt.Errorf("%w: unsupported sort field %q", model.ErrInvalidArgument, sortBy)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*model.User
+	for _, user := range r.users {
+		if userMatchesFilter(user, opts.Filter) {
+			matched = append(matched, user)
+		}
+	}
+
+	less := func(a, b *model.User) bool {
+		if c := compareUsers(a, b, sortBy); c != 0 {
+			return c < 0
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if opts.Descending {
+			return less(matched[j], matched[i])
+		}
+		return less(matched[i], matched[j])
+	})
+
+	start := 0
+	if opts.AfterID != "" {
+		last, ok := r.users[opts.AfterID]
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid page cursor", model.ErrInvalidArgument)
+		}
+		start = len(matched)
+		for i, user := range matched {
+			after := less(last, user)
+			if opts.Descending {
+				after = less(user, last)
+			}
+			if after {
+				start = i
+				break
+			}
+		}
+	}
+
+	page := &UserPage{TotalCount: int64(len(matched))}
+	end := start + opts.Limit
+	if end < len(matched) {
+		page.NextAfterID = matched[end-1].ID
+	} else {
+		end = len(matched)
+	}
+	for _, user := range matched[start:end] {
+		copied := *user
+		page.Users = append(page.Users, &copied)
+	}
+	return page, nil
+}
+
+func (r *memoryRepository) ScanUsers(ctx context.Context, afterID string, limit int) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*model.User
+	for id, user := range r.users {
+		if id > afterID {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryRepository) GetUserRoles(ctx context.Context, userID string) ([]model.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var roles []model.Role
+	for role := range r.roles[userID] {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles, nil
+}
+
+func (r *memoryRepository) GrantRole(ctx context.Context, grant *model.UserRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[grant.UserID]; !ok {
+		return model.ErrUserNotFound
+	}
+	if _, ok := r.roles[grant.UserID][grant.Role]; ok {
+		return nil
+	}
+	if r.roles[grant.UserID] == nil {
+		r.roles[grant.UserID] = make(map[model.Role]model.UserRole)
+	}
+	if grant.CreatedAt.IsZero() {
+		grant.CreatedAt = time.Now()
+	}
+	r.roles[grant.UserID][grant.Role] = *grant
+	return nil
+}
+
+func (r *memoryRepository) RevokeRole(ctx context.Context, userID string, role model.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return model.ErrUserNotFound
+	}
+	delete(r.roles[userID], role)
+	return nil
+}
+
+func (r *memoryRepository) AddAddress(ctx context.Context, userID string, address *model.UserAddress, limit int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return "", model.ErrUserNotFound
+	}
+
+	count := len(r.userAddresses(userID))
+	if limit > 0 && count >= limit {
+		return "", fmt.Errorf("%w: a user can have at most %d addresses", model.ErrAddressLimit, limit)
+	}
+
+	address.ID = newAddressID()
+	address.UserID = userID
+	address.IsDefault = count == 0
+	if address.CreatedAt.IsZero() {
+		address.CreatedAt = time.Now()
+	}
+
+	stored := *address
+	r.addresses[address.ID] = &stored
+	r.addressOrder = append(r.addressOrder, address.ID)
+	return address.ID, nil
+}
+
+func (r *memoryRepository) GetAddresses(ctx context.Context, userID string) ([]*model.UserAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var addresses []*model.UserAddress
+	for _, address := range r.userAddresses(userID) {
+		copied := *address
+		addresses = append(addresses, &copied)
+	}
+	sort.SliceStable(addresses, func(i, j int) bool { return addresses[i].IsDefault && !addresses[j].IsDefault })
+	return addresses, nil
+}
+
+func (r *memoryRepository) EditAddress(ctx context.Context, userID, addressID string, address *model.UserAddress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.addresses[addressID]
+	if !ok || existing.UserID != userID {
+		return model.ErrAddressNotFound
+	}
+
+	existing.StreetName = address.StreetName
+	existing.Locality = address.Locality
+	existing.State = address.State
+	existing.Pincode = address.Pincode
+	existing.Latitude = address.Latitude
+	existing.Longitude = address.Longitude
+	return nil
+}
+
+func (r *memoryRepository) DeleteAddress(ctx context.Context, userID, addressID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.addresses[addressID]
+	if !ok || existing.UserID != userID {
+		return model.ErrAddressNotFound
+	}
+
+	delete(r.addresses, addressID)
+	for i, id := range r.addressOrder {
+		if id == addressID {
+			r.addressOrder = append(r.addressOrder[:i], r.addressOrder[i+1:]...)
+			break
+		}
+	}
+	if remaining := r.userAddresses(userID); existing.IsDefault && len(remaining) > 0 {
+		remaining[0].IsDefault = true
+	}
+	return nil
+}
+
+func (r *memoryRepository) SetDefaultAddress(ctx context.Context, userID, addressID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.addresses[addressID]
+	if !ok || existing.UserID != userID {
+		return model.ErrAddressNotFound
+	}
+	for _, address := range r.userAddresses(userID) {
+		address.IsDefault = address.ID == addressID
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateAddressDetails(ctx context.Context, userID, addressID string, details *model.UserAddress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.addresses[addressID]
+	if !ok || existing.UserID != userID {
+		return model.ErrAddressNotFound
+	}
+
+	existing.Label = details.Label
+	existing.Landmark = details.Landmark
+	existing.FlatNumber = details.FlatNumber
+	existing.DeliveryInstructions = details.DeliveryInstructions
+	return nil
+}
+
+// userAddresses returns the stored addresses of a user, oldest first. It must
+// be called with the lock held.
+func (r *memoryRepository) userAddresses(userID string) []*model.UserAddress {
+	var addresses []*model.UserAddress
+	for _, id := range r.addressOrder {
+		if address, ok := r.addresses[id]; ok && address.UserID == userID {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func (r *memoryRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insertSession(session)
+}
+
+func (r *memoryRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.RefreshTokenHash == tokenHash {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, model.ErrSessionNotFound
+}
+
+func (r *memoryRepository) RotateSession(ctx context.Context, currentID string, next *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.sessions[currentID]
+	if !ok || current.RotatedAt != nil || current.RevokedAt != nil {
+		return model.ErrRefreshTokenReused
+	}
+
+	if err := r.insertSession(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	current.RotatedAt = &now
+	return nil
+}
+
+func (r *memoryRepository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	r.revokeSessions(func(session *model.Session) bool { return session.FamilyID == familyID })
+	return nil
+}
+
+func (r *memoryRepository) RevokeUserSessions(ctx context.Context, userID string) error {
+	r.revokeSessions(func(session *model.Session) bool { return session.UserID == userID })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[userID]; ok {
+		user.TokenVersion++
+	}
+	return nil
+}
+
+func (r *memoryRepository) IsSessionFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.FamilyID == familyID && session.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, limit SendLimit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[token.UserID]
+	if !ok {
+		return model.ErrUserNotFound
+	}
+	now := time.Now()
+	sends, ok := limit.next(now, user.PasswordResetSentAt, user.PasswordResetSends)
+	if !ok {
+		return fmt.Errorf("%w: password reset requested too often", model.ErrTooManyAttempts)
+	}
+	user.PasswordResetSentAt = &now
+	user.PasswordResetSends = sends
+
+	for _, existing := range r.passwordResets {
+		if existing.UserID == token.UserID && existing.UsedAt == nil {
+			existing.UsedAt = &now
+		}
+	}
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now
+	}
+	stored := *token
+	r.passwordResets[token.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var token *model.PasswordResetToken
+	for _, candidate := range r.passwordResets {
+		if candidate.TokenHash == tokenHash && candidate.UsedAt == nil && candidate.ExpiresAt.After(now) {
+			token = candidate
+			break
+		}
+	}
+	if token == nil {
+		return "", model.ErrInvalidToken
+	}
+
+	user, ok := r.users[token.UserID]
+	if !ok {
+		return "", model.ErrUserNotFound
+	}
+
+	token.UsedAt = &now
+	user.PasswordHash = passwordHash
+	user.TokenVersion++
+	for _, session := range r.sessions {
+		if session.UserID == user.ID && session.RevokedAt == nil {
+			revokedAt := now
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return user.ID, nil
+}
+
+// findUserByEmail must be called with the lock held
+func (r *memoryRepository) findUserByEmail(email string) *model.User {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+// updateUser applies fn to the stored user under the write lock
+func (r *memoryRepository) updateUser(userID string, fn func(user *model.User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return model.ErrUserNotFound
+	}
+	return fn(user)
+}
+
+// insertSession must be called with the write lock held
+func (r *memoryRepository) insertSession(session *model.Session) error {
+	if _, exists := r.sessions[session.ID]; exists {
+		return fmt.Errorf("failed to create session: duplicate id %q", session.ID)
+	}
+	for _, existing := range r.sessions {
+		if existing.RefreshTokenHash == session.RefreshTokenHash {
+			return fmt.Errorf("failed to create session: duplicate refresh token")
+		}
+	}
+
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) revokeSessions(match func(session *model.Session) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range r.sessions {
+		if session.RevokedAt == nil && match(session) {
+			revokedAt := now
+			session.RevokedAt = &revokedAt
+		}
+	}
+}
+
+func userMatchesFilter(user *model.User, filter UserFilter) bool {
+	if filter.IsBanned != nil && user.IsBanned != *filter.IsBanned {
+		return false
+	}
+	if filter.IsVerified != nil && user.IsVerified != *filter.IsVerified {
+		return false
+	}
+	if filter.MinReputation != nil && user.Reputation < *filter.MinReputation {
+		return false
+	}
+	if filter.MaxReputation != nil && user.Reputation > *filter.MaxReputation {
+		return false
+	}
+	if filter.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(filter.Email)) {
+		return false
+	}
+	if filter.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	return true
+}
+
+// compareUsers orders two users by the sort column only
+func compareUsers(a, b *model.User, sortBy UserSortField) int {
+	switch sortBy {
+	case SortByName:
+		return strings.Compare(a.Name, b.Name)
+	case SortByEmail:
+		return strings.Compare(a.Email, b.Email)
+	case SortByReputation:
+		switch {
+		case a.Reputation < b.Reputation:
+			return -1
+		case a.Reputation > b.Reputation:
+			return 1
+		}
+		return 0
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
@@ -0,0 +1,9 @@
+package repository
+
+import "testing"
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	runUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewMemoryRepository()
+	})
+}
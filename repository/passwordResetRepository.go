@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+)
+
+// CreatePasswordResetToken stores a new reset token and invalidates any
+// earlier unused tokens of the same user. It fails with ErrTooManyAttempts
+// when limit does not allow another token yet.
+func (r *userRepository) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, limit SendLimit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, token.UserID); err != nil {
+			return err
+		}
+		var user model.User
+		if err := tx.Select("password_reset_sent_at", "password_reset_sends").Where("id = ?", token.UserID).First(&user).Error; err != nil {
+			return fmt.Errorf("failed to read password reset state: %w", err)
+		}
+		now := time.Now()
+		sends, ok := limit.next(now, user.PasswordResetSentAt, user.PasswordResetSends)
+		if !ok {
+			return fmt.Errorf("%w: password reset requested too often", model.ErrTooManyAttempts)
+		}
+		err := tx.Model(&model.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password_reset_sent_at": now,
+			"password_reset_sends":   sends,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to record password reset: %w", err)
+		}
+
+		if err := tx.Model(&model.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+		}
+
+		if err := tx.Create(token).Error; err != nil {
+			return fmt.Errorf("failed to create reset token: %w", err)
+		}
+		return nil
+	})
+}
+
+// ResetPassword consumes an unused, unexpired reset token, replaces the
+// user's password hash and revokes all of their sessions in one transaction.
+// It returns the ID of the user whose password was reset.
+func (r *userRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	var userID string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var token model.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrInvalidToken
+			}
+			return fmt.Errorf("failed to find reset token: %w", err)
+		}
+
+		// Guard against the same token being redeemed concurrently
+		result := tx.Model(&model.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to consume reset token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrInvalidToken
+		}
+
+		result = tx.Model(&model.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password_hash": passwordHash,
+			"token_version": gorm.Expr("token_version + ?", 1),
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update password: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrUserNotFound
+		}
+
+		if err := tx.Model(&model.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke user sessions: %w", err)
+		}
+
+		userID = token.UserID
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
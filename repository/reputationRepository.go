@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+)
+
+// ReputationAdjustment is the outcome of AdjustReputation
+type ReputationAdjustment struct {
+	// Event is the recorded ledger entry, the original one when Replayed
+	Event *model.ReputationEvent
+	// Reputation is the user's score after the adjustment
+	Reputation int32
+	// Replayed is set when the idempotency key was already used and nothing changed
+	Replayed bool
+}
+
+// LeaderboardEntry is a user and their rank by reputation. Users with the
+// same reputation share a rank and the next rank is skipped, as in 1, 2, 2, 4.
+type LeaderboardEntry struct {
+	Rank int64
+	User *model.User
+}
+
+// AdjustReputation appends event to the ledger and sets the user's cached
+// reputation to the new ledger sum in the same transaction. An event whose
+// idempotency key was already recorded for its source service is not applied
+// again; retrying it returns the original event, and reusing the key for a
+// different adjustment fails with ErrIdempotencyReused.
+func (r *userRepository) AdjustReputation(ctx context.Context, event *model.ReputationEvent) (*ReputationAdjustment, error) {
+	adjustment, err := r.adjustReputation(ctx, event)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// A concurrent call with the same key committed first, so this is a replay
+		return r.adjustReputation(ctx, event)
+	}
+	return adjustment, err
+}
+
+func (r *userRepository) adjustReputation(ctx context.Context, event *model.ReputationEvent) (*ReputationAdjustment, error) {
+	var adjustment *ReputationAdjustment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the user serialises adjustments so each sees the previous sum
+		if err := lockUser(tx, event.UserID); err != nil {
+			return err
+		}
+
+		var existing model.ReputationEvent
+		err := tx.Where("source_service = ? AND idempotency_key = ?", event.SourceService, event.IdempotencyKey).
+			First(&existing).Error
+		switch {
+		case err == nil:
+			if existing.UserID != event.UserID || existing.Delta != event.Delta {
+				return model.ErrIdempotencyReused
+			}
+			reputation, err := userReputation(tx, event.UserID)
+			if err != nil {
+				return err
+			}
+			adjustment = &ReputationAdjustment{Event: &existing, Reputation: reputation, Replayed: true}
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("failed to look up reputation event: %w", err)
+		}
+
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to record reputation event: %w", err)
+		}
+		ledgerSum := tx.Model(&model.ReputationEvent{}).Select("COALESCE(SUM(delta), 0)").Where("user_id = ?", event.UserID)
+		if err := tx.Model(&model.User{}).Where("id = ?", event.UserID).Update("reputation", ledgerSum).Error; err != nil {
+			return fmt.Errorf("failed to update reputation: %w", err)
+		}
+
+		reputation, err := userReputation(tx, event.UserID)
+		if err != nil {
+			return err
+		}
+		adjustment = &ReputationAdjustment{Event: event, Reputation: reputation}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+func userReputation(tx *gorm.DB, userID string) (int32, error) {
+	var user model.User
+	if err := tx.Select("reputation").Where("id = ?", userID).First(&user).Error; err != nil {
+		return 0, fmt.Errorf("failed to read reputation: %w", err)
+	}
+	return user.Reputation, nil
+}
+
+// GetLeaderboard returns the limit unbanned users with the highest reputation
+func (r *userRepository) GetLeaderboard(ctx context.Context, limit int) ([]*LeaderboardEntry, error) {
+	var users []*model.User
+	err := r.db.WithContext(ctx).Where("is_banned = ?", false).
+		Order("reputation DESC").Order("id").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	return rankUsers(users), nil
+}
+
+// GetReputationRank returns the leaderboard rank of a user, or 0 when the
+// user is banned and therefore not ranked
+func (r *userRepository) GetReputationRank(ctx context.Context, userID string) (int64, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Select("is_banned", "reputation").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, model.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.IsBanned {
+		return 0, nil
+	}
+
+	var ahead int64
+	err := r.db.WithContext(ctx).Model(&model.User{}).
+		Where("is_banned = ? AND reputation > ?", false, user.Reputation).Count(&ahead).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to rank user: %w", err)
+	}
+	return ahead + 1, nil
+}
+
+// rankUsers ranks users already sorted by descending reputation, starting at the top
+func rankUsers(users []*model.User) []*LeaderboardEntry {
+	entries := make([]*LeaderboardEntry, len(users))
+	for i, user := range users {
+		rank := int64(i + 1)
+		if i > 0 && user.Reputation == users[i-1].Reputation {
+			rank = entries[i-1].Rank
+		}
+		entries[i] = &LeaderboardEntry{Rank: rank, User: user}
+	}
+	return entries
+}
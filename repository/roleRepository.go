@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserRoles returns the roles explicitly granted to a user, ordered by name.
+// RoleUser is implicit and never returned.
+func (r *userRepository) GetUserRoles(ctx context.Context, userID string) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.WithContext(ctx).Model(&model.UserRole{}).
+		Where("user_id = ?", userID).Order("role").Pluck("role", &roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GrantRole gives a user a role. Granting a role the user already holds is a no-op.
+func (r *userRepository) GrantRole(ctx context.Context, grant *model.UserRole) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireUser(tx, grant.UserID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(grant).Error; err != nil {
+			return fmt.Errorf("failed to grant role: %w", err)
+		}
+		return nil
+	})
+}
+
+// RevokeRole removes a role from a user. Revoking a role the user does not hold is a no-op.
+func (r *userRepository) RevokeRole(ctx context.Context, userID string, role model.Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireUser(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND role = ?", userID, role).Delete(&model.UserRole{}).Error; err != nil {
+			return fmt.Errorf("failed to revoke role: %w", err)
+		}
+		return nil
+	})
+}
+
+// requireUser returns ErrUserNotFound unless the user exists
+func requireUser(tx *gorm.DB, userID string) error {
+	var count int64
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if count == 0 {
+		return model.ErrUserNotFound
+	}
+	return nil
+}
+
+// lockUser locks the user row until the transaction ends, so writes that must
+// see each other's results run one at a time per user. It returns
+// ErrUserNotFound unless the user exists.
+func lockUser(tx *gorm.DB, userID string) error {
+	var user model.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrUserNotFound
+		}
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+)
+
+// CreateSession stores a new refresh-token session
+func (r *userRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionByTokenHash retrieves a session by the hash of its refresh token
+func (r *userRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+	var session model.Session
+	if err := r.db.WithContext(ctx).Where("refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session, nil
+}
+
+// RotateSession marks the current session as rotated and stores its successor
+// in a single transaction. It fails with ErrRefreshTokenReused if the current
+// session was already rotated or revoked, e.g. by a concurrent refresh.
+func (r *userRepository) RotateSession(ctx context.Context, currentID string, next *model.Session) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Session{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", currentID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate session: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return model.ErrRefreshTokenReused
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		return nil
+	})
+}
+
+// RevokeSessionFamily revokes every session descended from the same login
+func (r *userRepository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	result := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session family: %w", result.Error)
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every session of a user across all devices and
+// bumps their token version, so access tokens issued without a session die too
+func (r *userRepository) RevokeUserSessions(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return fmt.Errorf("failed to revoke user sessions: %w", err)
+		}
+		err = tx.Model(&model.User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + ?", 1)).Error
+		if err != nil {
+			return fmt.Errorf("failed to revoke user tokens: %w", err)
+		}
+		return nil
+	})
+}
+
+// IsSessionFamilyActive reports whether a session family has not been revoked
+func (r *userRepository) IsSessionFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check session family: %w", err)
+	}
+	return count > 0, nil
+}
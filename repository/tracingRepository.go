@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+)
+
+const tracerName = "github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+
+// tracingRepository wraps a UserRepository and records a span for every call,
+// so queries show up as children of the RPC that issued them
+type tracingRepository struct {
+	next   UserRepository
+	tracer trace.Tracer
+}
+
+// NewTracingRepository decorates next with OpenTelemetry spans using the global tracer provider
+func NewTracingRepository(next UserRepository) UserRepository {
+	return &tracingRepository{next: next, tracer: otel.Tracer(tracerName)}
+}
+
+func (r *tracingRepository) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "UserRepository."+method, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (r *tracingRepository) CreateUser(ctx context.Context, user *model.User) error {
+	ctx, span := r.start(ctx, "CreateUser")
+	err := r.next.CreateUser(ctx, user)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, span := r.start(ctx, "GetUserByEmail")
+	result, err := r.next.GetUserByEmail(ctx, email)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	ctx, span := r.start(ctx, "GetUserByID")
+	result, err := r.next.GetUserByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error {
+	ctx, span := r.start(ctx, "UpdateUserVerification")
+	err := r.next.UpdateUserVerification(ctx, userID, isVerified)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) GetUserProfile(ctx context.Context, userID string) (*model.User, error) {
+	ctx, span := r.start(ctx, "GetUserProfile")
+	result, err := r.next.GetUserProfile(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) UpdateUser(ctx context.Context, user *model.User) error {
+	ctx, span := r.start(ctx, "UpdateUser")
+	err := r.next.UpdateUser(ctx, user)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	ctx, span := r.start(ctx, "UpdatePassword")
+	err := r.next.UpdatePassword(ctx, userID, passwordHash)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) SetPendingEmail(ctx context.Context, userID, email, codeHash string, expiresAt time.Time) error {
+	ctx, span := r.start(ctx, "SetPendingEmail")
+	err := r.next.SetPendingEmail(ctx, userID, email, codeHash, expiresAt)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) IncrementEmailChangeAttempts(ctx context.Context, userID string) error {
+	ctx, span := r.start(ctx, "IncrementEmailChangeAttempts")
+	err := r.next.IncrementEmailChangeAttempts(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) ConfirmEmailChange(ctx context.Context, userID string) error {
+	ctx, span := r.start(ctx, "ConfirmEmailChange")
+	err := r.next.ConfirmEmailChange(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) StoreVerificationCode(ctx context.Context, userID, codeHash string, expiresAt time.Time, limit SendLimit) error {
+	ctx, span := r.start(ctx, "StoreVerificationCode")
+	err := r.next.StoreVerificationCode(ctx, userID, codeHash, expiresAt, limit)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) GetVerificationCode(ctx context.Context, userID string) (string, error) {
+	ctx, span := r.start(ctx, "GetVerificationCode")
+	result, err := r.next.GetVerificationCode(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) IncrementVerificationAttempts(ctx context.Context, userID string) error {
+	ctx, span := r.start(ctx, "IncrementVerificationAttempts")
+	err := r.next.IncrementVerificationAttempts(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) CheckBan(ctx context.Context, userID string) (bool, error) {
+	ctx, span := r.start(ctx, "CheckBan")
+	result, err := r.next.CheckBan(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) BanUser(ctx context.Context, ban *model.UserBan) error {
+	ctx, span := r.start(ctx, "BanUser")
+	err := r.next.BanUser(ctx, ban)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) UnBanUser(ctx context.Context, userID, liftedBy string) error {
+	ctx, span := r.start(ctx, "UnBanUser")
+	err := r.next.UnBanUser(ctx, userID, liftedBy)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) GetBanHistory(ctx context.Context, userID string) ([]*model.UserBan, error) {
+	ctx, span := r.start(ctx, "GetBanHistory")
+	result, err := r.next.GetBanHistory(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) LiftExpiredBans(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := r.start(ctx, "LiftExpiredBans")
+	result, err := r.next.LiftExpiredBans(ctx, now)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) ListUsers(ctx context.Context, opts UserListOptions) (*UserPage, error) {
+	ctx, span := r.start(ctx, "ListUsers")
+	result, err := r.next.ListUsers(ctx, opts)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) ScanUsers(ctx context.Context, afterID string, limit int) ([]*model.User, error) {
+	ctx, span := r.start(ctx, "ScanUsers")
+	result, err := r.next.ScanUsers(ctx, afterID, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GetUserRoles(ctx context.Context, userID string) ([]model.Role, error) {
+	ctx, span := r.start(ctx, "GetUserRoles")
+	result, err := r.next.GetUserRoles(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GrantRole(ctx context.Context, grant *model.UserRole) error {
+	ctx, span := r.start(ctx, "GrantRole")
+	err := r.next.GrantRole(ctx, grant)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) RevokeRole(ctx context.Context, userID string, role model.Role) error {
+	ctx, span := r.start(ctx, "RevokeRole")
+	err := r.next.RevokeRole(ctx, userID, role)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) AdjustReputation(ctx context.Context, event *model.ReputationEvent) (*ReputationAdjustment, error) {
+	ctx, span := r.start(ctx, "AdjustReputation")
+	result, err := r.next.AdjustReputation(ctx, event)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GetLeaderboard(ctx context.Context, limit int) ([]*LeaderboardEntry, error) {
+	ctx, span := r.start(ctx, "GetLeaderboard")
+	result, err := r.next.GetLeaderboard(ctx, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GetReputationRank(ctx context.Context, userID string) (int64, error) {
+	ctx, span := r.start(ctx, "GetReputationRank")
+	result, err := r.next.GetReputationRank(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) AddAddress(ctx context.Context, userID string, address *model.UserAddress, limit int) (string, error) {
+	ctx, span := r.start(ctx, "AddAddress")
+	result, err := r.next.AddAddress(ctx, userID, address, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) GetAddresses(ctx context.Context, userID string) ([]*model.UserAddress, error) {
+	ctx, span := r.start(ctx, "GetAddresses")
+	result, err := r.next.GetAddresses(ctx, userID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) EditAddress(ctx context.Context, userID, addressID string, address *model.UserAddress) error {
+	ctx, span := r.start(ctx, "EditAddress")
+	err := r.next.EditAddress(ctx, userID, addressID, address)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) DeleteAddress(ctx context.Context, userID, addressID string) error {
+	ctx, span := r.start(ctx, "DeleteAddress")
+	err := r.next.DeleteAddress(ctx, userID, addressID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) SetDefaultAddress(ctx context.Context, userID, addressID string) error {
+	ctx, span := r.start(ctx, "SetDefaultAddress")
+	err := r.next.SetDefaultAddress(ctx, userID, addressID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) UpdateAddressDetails(ctx context.Context, userID, addressID string, details *model.UserAddress) error {
+	ctx, span := r.start(ctx, "UpdateAddressDetails")
+	err := r.next.UpdateAddressDetails(ctx, userID, addressID, details)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	ctx, span := r.start(ctx, "CreateSession")
+	err := r.next.CreateSession(ctx, session)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+	ctx, span := r.start(ctx, "GetSessionByTokenHash")
+	result, err := r.next.GetSessionByTokenHash(ctx, tokenHash)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) RotateSession(ctx context.Context, currentID string, next *model.Session) error {
+	ctx, span := r.start(ctx, "RotateSession")
+	err := r.next.RotateSession(ctx, currentID, next)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	ctx, span := r.start(ctx, "RevokeSessionFamily")
+	err := r.next.RevokeSessionFamily(ctx, familyID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) RevokeUserSessions(ctx context.Context, userID string) error {
+	ctx, span := r.start(ctx, "RevokeUserSessions")
+	err := r.next.RevokeUserSessions(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) IsSessionFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	ctx, span := r.start(ctx, "IsSessionFamilyActive")
+	result, err := r.next.IsSessionFamilyActive(ctx, familyID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (r *tracingRepository) CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, limit SendLimit) error {
+	ctx, span := r.start(ctx, "CreatePasswordResetToken")
+	err := r.next.CreatePasswordResetToken(ctx, token, limit)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracingRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	ctx, span := r.start(ctx, "ResetPassword")
+	result, err := r.next.ResetPassword(ctx, tokenHash, passwordHash)
+	endSpan(span, err)
+	return result, err
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "testing"
+
+// The decorator must delegate every call unchanged
+func TestTracingRepositoryContract(t *testing.T) {
+	runUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewTracingRepository(NewMemoryRepository())
+	})
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"gorm.io/gorm"
+)
+
+// UserSortField is a column ListUsers can order by
+type UserSortField string
+
+const (
+	SortByCreatedAt  UserSortField = "created_at"
+	SortByName       UserSortField = "name"
+	SortByEmail      UserSortField = "email"
+	SortByReputation UserSortField = "reputation"
+)
+
+// UserFilter narrows down ListUsers results. Nil pointers and empty strings
+// leave the corresponding column unfiltered.
+type UserFilter struct {
+	IsBanned      *bool
+	IsVerified    *bool
+	MinReputation *int32
+	MaxReputation *int32
+	// Email and Name match case-insensitive substrings
+	Email string
+	Name  string
+}
+
+// UserListOptions controls a single ListUsers page
+type UserListOptions struct {
+	Filter     UserFilter
+	SortBy     UserSortField
+	Descending bool
+	Limit      int
+	// AfterID is the ID of the last user of the previous page, empty for the first page
+	AfterID string
+}
+
+// UserPage is one page of ListUsers results
+type UserPage struct {
+	Users []*model.User
+	// NextAfterID is the cursor for the following page, empty on the last page
+	NextAfterID string
+	TotalCount  int64
+}
+
+// ListUsers returns one page of users using keyset pagination on the sort
+// column with the user ID as tie breaker, so pages stay stable while rows are
+// inserted.
+func (r *userRepository) ListUsers(ctx context.Context, opts UserListOptions) (*UserPage, error) {
+	sortBy := opts.SortBy
+	switch sortBy {
+	case SortByCreatedAt, SortByName, SortByEmail, SortByReputation:
+	case "":
+		sortBy = SortByCreatedAt
+	default:
+		return nil, fmt.Errorf("%w: unsupported sort field %q", model.ErrInvalidArgument, sortBy)
+	}
+	column := string(sortBy)
+
+	query := applyUserFilter(r.db.WithContext(ctx).Model(&model.User{}), opts.Filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	if opts.AfterID != "" {
+		var last model.User
+		if err := r.db.WithContext(ctx).Where("id = ?", opts.AfterID).First(&last).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: invalid page cursor", model.ErrInvalidArgument)
+			}
+			return nil, fmt.Errorf("failed to resolve page cursor: %w", err)
+		}
+
+		op := ">"
+		if opts.Descending {
+			op = "<"
+		}
+		lastValue := userSortValue(&last, sortBy)
+		query = query.Where(
+			fmt.Sprintf("((%s %s ?) OR (%s = ? AND id %s ?))", column, op, column, op),
+			lastValue, lastValue, last.ID,
+		)
+	}
+
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+
+	var users []*model.User
+	// Fetch one extra row to find out whether another page follows
+	if err := query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(opts.Limit + 1).
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &UserPage{Users: users, TotalCount: total}
+	if len(users) > opts.Limit {
+		page.Users = users[:opts.Limit]
+		page.NextAfterID = page.Users[opts.Limit-1].ID
+	}
+	return page, nil
+}
+
+// ScanUsers returns up to limit users with an ID greater than afterID in ID
+// order, for walking the whole table in batches
+func (r *userRepository) ScanUsers(ctx context.Context, afterID string, limit int) ([]*model.User, error) {
+	var users []*model.User
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to scan users: %w", err)
+	}
+	return users, nil
+}
+
+func applyUserFilter(query *gorm.DB, filter UserFilter) *gorm.DB {
+	if filter.IsBanned != nil {
+		query = query.Where("is_banned = ?", *filter.IsBanned)
+	}
+	if filter.IsVerified != nil {
+		query = query.Where("is_verified = ?", *filter.IsVerified)
+	}
+	if filter.MinReputation != nil {
+		query = query.Where("reputation >= ?", *filter.MinReputation)
+	}
+	if filter.MaxReputation != nil {
+		query = query.Where("reputation <= ?", *filter.MaxReputation)
+	}
+	if filter.Email != "" {
+		query = query.Where("LOWER(email) LIKE ? ESCAPE '!'", containsPattern(filter.Email))
+	}
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '!'", containsPattern(filter.Name))
+	}
+	return query
+}
+
+// containsPattern builds a lower-cased LIKE pattern matching s anywhere,
+// escaping wildcards with '!'
+func containsPattern(s string) string {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(s))
+	return "%" + escaped + "%"
+}
+
+func userSortValue(user *model.User, sortBy UserSortField) interface{} {
+	switch sortBy {
+	case SortByName:
+		return user.Name
+	case SortByEmail:
+		return user.Email
+	case SortByReputation:
+		return user.Reputation
+	default:
+		return user.CreatedAt
+	}
+}
@@ -1,32 +1,63 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	CreateUser(user *model.User) error
-	GetUserByEmail(email string) (*model.User, error)
-	GetUserByID(id string) (*model.User, error)
-	UpdateUserVerification(userID string, isVerified bool) error
-	GetUserProfile(userID string) (*model.User, error)
-	UpdateUser(user *model.User) error
-	StoreVerificationCode(userID, code string) error
-	GetVerificationCode(userID string) (string, error)
-	CheckBan(userID string) (bool, error)
-	UnBanUser(userID string) error
-	BanUser(userID string) error
-	GetAllUsers() ([]*model.User, error)
-
-	AddAddress(userID string, address *model.UserAddress) (string, error)
-	GetAddresses(userID string) ([]*model.UserAddress, error)
-	EditAddress(userID, addressID string, address *model.UserAddress) error
-	DeleteAddress(userID, addressID string) error
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error
+	GetUserProfile(ctx context.Context, userID string) (*model.User, error)
+	UpdateUser(ctx context.Context, user *model.User) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	SetPendingEmail(ctx context.Context, userID, email, codeHash string, expiresAt time.Time) error
+	IncrementEmailChangeAttempts(ctx context.Context, userID string) error
+	ConfirmEmailChange(ctx context.Context, userID string) error
+	StoreVerificationCode(ctx context.Context, userID, codeHash string, expiresAt time.Time, limit SendLimit) error
+	GetVerificationCode(ctx context.Context, userID string) (string, error)
+	IncrementVerificationAttempts(ctx context.Context, userID string) error
+	CheckBan(ctx context.Context, userID string) (bool, error)
+	BanUser(ctx context.Context, ban *model.UserBan) error
+	UnBanUser(ctx context.Context, userID, liftedBy string) error
+	GetBanHistory(ctx context.Context, userID string) ([]*model.UserBan, error)
+	LiftExpiredBans(ctx context.Context, now time.Time) (int, error)
+	ListUsers(ctx context.Context, opts UserListOptions) (*UserPage, error)
+	ScanUsers(ctx context.Context, afterID string, limit int) ([]*model.User, error)
+
+	GetUserRoles(ctx context.Context, userID string) ([]model.Role, error)
+	GrantRole(ctx context.Context, grant *model.UserRole) error
+	RevokeRole(ctx context.Context, userID string, role model.Role) error
+
+	AdjustReputation(ctx context.Context, event *model.ReputationEvent) (*ReputationAdjustment, error)
+	GetLeaderboard(ctx context.Context, limit int) ([]*LeaderboardEntry, error)
+	GetReputationRank(ctx context.Context, userID string) (int64, error)
+
+	AddAddress(ctx context.Context, userID string, address *model.UserAddress, limit int) (string, error)
+	GetAddresses(ctx context.Context, userID string) ([]*model.UserAddress, error)
+	EditAddress(ctx context.Context, userID, addressID string, address *model.UserAddress) error
+	DeleteAddress(ctx context.Context, userID, addressID string) error
+	SetDefaultAddress(ctx context.Context, userID, addressID string) error
+	UpdateAddressDetails(ctx context.Context, userID, addressID string, details *model.UserAddress) error
+
+	CreateSession(ctx context.Context, session *model.Session) error
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error)
+	RotateSession(ctx context.Context, currentID string, next *model.Session) error
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	RevokeUserSessions(ctx context.Context, userID string) error
+	IsSessionFamilyActive(ctx context.Context, familyID string) (bool, error)
+
+	CreatePasswordResetToken(ctx context.Context, token *model.PasswordResetToken, limit SendLimit) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error)
 }
 
 type userRepository struct {
@@ -37,94 +68,197 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-// Modify the existing userRepository to implement the new methods
-func (r *userRepository) AddAddress(userID string, address *model.UserAddress) (string, error) {
-	// Generate a unique address ID
-	address.ID = fmt.Sprintf("addr_%d", time.Now().UnixNano())
+// newAddressID returns a random address ID, which cannot collide the way
+// time-based IDs did under concurrent inserts
+func newAddressID() string {
+	return fmt.Sprintf("addr_%s", uuid.New().String())
+}
+
+// AddAddress stores a new address for a user. A user's first address becomes
+// their default. Users with limit addresses already get ErrAddressLimit; a
+// limit of 0 allows any number.
+func (r *userRepository) AddAddress(ctx context.Context, userID string, address *model.UserAddress, limit int) (string, error) {
+	address.ID = newAddressID()
 	address.UserID = userID
 
-	if err := r.db.Create(address).Error; err != nil {
-		return "", fmt.Errorf("failed to add address: %w", err)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&model.UserAddress{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count addresses: %w", err)
+		}
+		// The user lock keeps concurrent inserts from both passing the check
+		if limit > 0 && count >= int64(limit) {
+			return fmt.Errorf("%w: a user can have at most %d addresses", model.ErrAddressLimit, limit)
+		}
+		address.IsDefault = count == 0
+
+		if err := tx.Create(address).Error; err != nil {
+			return fmt.Errorf("failed to add address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return address.ID, nil
 }
 
-func (r *userRepository) GetAddresses(userID string) ([]*model.UserAddress, error) {
+// GetAddresses returns a user's addresses, the default first and the rest oldest first
+func (r *userRepository) GetAddresses(ctx context.Context, userID string) ([]*model.UserAddress, error) {
 	var addresses []*model.UserAddress
-	if err := r.db.Where("user_id = ?", userID).Find(&addresses).Error; err != nil {
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("is_default DESC").Order("created_at").Order("id").Find(&addresses).Error
+	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve addresses: %w", err)
 	}
 
 	return addresses, nil
 }
 
-func (r *userRepository) EditAddress(userID, addressID string, address *model.UserAddress) error {
-	// First, verify the address belongs to the user
-	var existingAddress model.UserAddress
-	if err := r.db.Where("id = ? AND user_id = ?", addressID, userID).First(&existingAddress).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("address not found or does not belong to user")
-		}
-		return fmt.Errorf("failed to find address: %w", err)
-	}
-
-	// Update the address
-	result := r.db.Model(&existingAddress).Updates(map[string]interface{}{
-		"street_name": address.StreetName,
-		"locality":    address.Locality,
-		"state":       address.State,
-		"pincode":     address.Pincode,
-	})
-
+func (r *userRepository) EditAddress(ctx context.Context, userID, addressID string, address *model.UserAddress) error {
+	result := r.db.WithContext(ctx).Model(&model.UserAddress{}).
+		Where("id = ? AND user_id = ?", addressID, userID).
+		Updates(map[string]interface{}{
+			"street_name": address.StreetName,
+			"locality":    address.Locality,
+			"state":       address.State,
+			"pincode":     address.Pincode,
+			"latitude":    address.Latitude,
+			"longitude":   address.Longitude,
+		})
 	if result.Error != nil {
 		return fmt.Errorf("failed to update address: %w", result.Error)
 	}
-
 	if result.RowsAffected == 0 {
-		return errors.New("no rows updated")
+		return model.ErrAddressNotFound
 	}
-
 	return nil
 }
 
-func (r *userRepository) DeleteAddress(userID, addressID string) error {
-	// Delete the address, ensuring it belongs to the user
-	result := r.db.Where("id = ? AND user_id = ?", addressID, userID).Delete(&model.UserAddress{})
+// DeleteAddress removes an address. When it was the default, the user's
+// oldest remaining address becomes the default.
+func (r *userRepository) DeleteAddress(ctx context.Context, userID, addressID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			if errors.Is(err, model.ErrUserNotFound) {
+				return model.ErrAddressNotFound
+			}
+			return err
+		}
+		address, err := findAddress(tx, userID, addressID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(address).Error; err != nil {
+			return fmt.Errorf("failed to delete address: %w", err)
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next model.UserAddress
+		err = tx.Where("user_id = ?", userID).Order("created_at").Order("id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find next default address: %w", err)
+		}
+		if err := tx.Model(&next).Update("is_default", true).Error; err != nil {
+			return fmt.Errorf("failed to set default address: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetDefaultAddress makes an address the user's default and clears the previous one
+func (r *userRepository) SetDefaultAddress(ctx context.Context, userID, addressID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			if errors.Is(err, model.ErrUserNotFound) {
+				return model.ErrAddressNotFound
+			}
+			return err
+		}
+		if _, err := findAddress(tx, userID, addressID); err != nil {
+			return err
+		}
+
+		err := tx.Model(&model.UserAddress{}).
+			Where("user_id = ? AND id <> ? AND is_default = ?", userID, addressID, true).
+			Update("is_default", false).Error
+		if err != nil {
+			return fmt.Errorf("failed to clear default address: %w", err)
+		}
+		if err := tx.Model(&model.UserAddress{}).Where("id = ?", addressID).Update("is_default", true).Error; err != nil {
+			return fmt.Errorf("failed to set default address: %w", err)
+		}
+		return nil
+	})
+}
 
+// UpdateAddressDetails replaces the label, landmark, flat number and delivery
+// instructions of an address
+func (r *userRepository) UpdateAddressDetails(ctx context.Context, userID, addressID string, details *model.UserAddress) error {
+	result := r.db.WithContext(ctx).Model(&model.UserAddress{}).
+		Where("id = ? AND user_id = ?", addressID, userID).
+		Updates(map[string]interface{}{
+			"label":                 details.Label,
+			"landmark":              details.Landmark,
+			"flat_number":           details.FlatNumber,
+			"delivery_instructions": details.DeliveryInstructions,
+		})
 	if result.Error != nil {
-		return fmt.Errorf("failed to delete address: %w", result.Error)
+		return fmt.Errorf("failed to update address details: %w", result.Error)
 	}
-
 	if result.RowsAffected == 0 {
-		return errors.New("address not found or does not belong to user")
+		return model.ErrAddressNotFound
 	}
-
 	return nil
 }
 
-func (r *userRepository) GetAllUsers() ([]*model.User, error) {
-	var users []*model.User
-	if err := r.db.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("failed to get all users: %w", err)
+// findAddress returns ErrAddressNotFound unless the address belongs to the user
+func findAddress(tx *gorm.DB, userID, addressID string) (*model.UserAddress, error) {
+	var address model.UserAddress
+	if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.ErrAddressNotFound
+		}
+		return nil, fmt.Errorf("failed to find address: %w", err)
 	}
-	return users, nil
+	return &address, nil
+}
+
+// normalizeEmail returns the form an email is stored and looked up in. MySQL
+// compares emails case-insensitively while PostgreSQL and SQLite do not, so
+// emails are lower-cased to keep them unique on every database.
+func normalizeEmail(email string) string {
+	return strings.ToLower(email)
 }
 
 // CreateUser creates a new user record
-func (r *userRepository) CreateUser(user *model.User) error {
-	if err := r.db.Create(user).Error; err != nil {
+func (r *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	user.Email = normalizeEmail(user.Email)
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return model.ErrDuplicateEmail
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
 }
 
 // GetUserByEmail retrieves a user by their email address
-func (r *userRepository) GetUserByEmail(email string) (*model.User, error) {
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", normalizeEmail(email)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, model.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -132,34 +266,42 @@ func (r *userRepository) GetUserByEmail(email string) (*model.User, error) {
 }
 
 // GetUserByID retrieves a user by their ID
-func (r *userRepository) GetUserByID(id string) (*model.User, error) {
+func (r *userRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
 
 	var user model.User
-	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, model.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 	return &user, nil
 }
 
-// UpdateUserVerification updates the verification status of a user
-func (r *userRepository) UpdateUserVerification(userID string, isVerified bool) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("is_verified", isVerified)
+// UpdateUserVerification updates the verification status of a user and
+// discards any pending verification code once verified
+func (r *userRepository) UpdateUserVerification(ctx context.Context, userID string, isVerified bool) error {
+	updates := map[string]interface{}{"is_verified": isVerified}
+	if isVerified {
+		updates["verification_code"] = ""
+		updates["verification_expires_at"] = nil
+		updates["verification_attempts"] = 0
+	}
+
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(updates)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update user verification: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return model.ErrUserNotFound
 	}
 	return nil
 }
 
 // GetUserProfile retrieves the user profile by userID
-func (r *userRepository) GetUserProfile(userID string) (*model.User, error) {
+func (r *userRepository) GetUserProfile(ctx context.Context, userID string) (*model.User, error) {
 	var user model.User
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).First(&user)
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, model.ErrUserNotFound
@@ -170,8 +312,8 @@ func (r *userRepository) GetUserProfile(userID string) (*model.User, error) {
 }
 
 // UpdateUser updates a user's information
-func (r *userRepository) UpdateUser(user *model.User) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", user.ID).
+func (r *userRepository) UpdateUser(ctx context.Context, user *model.User) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", user.ID).
 		Updates(map[string]interface{}{
 			"name":         user.Name,
 			"phone_number": user.PhoneNumber,
@@ -180,79 +322,168 @@ func (r *userRepository) UpdateUser(user *model.User) error {
 		return fmt.Errorf("failed to update user: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return model.ErrUserNotFound
 	}
 	return nil
 }
 
-// StoreVerificationCode stores the verification code for a user
-func (r *userRepository) StoreVerificationCode(userID, code string) error {
-	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("verification_code", code)
+// UpdatePassword replaces a user's password hash and bumps their token
+// version, which invalidates every access token issued before
+func (r *userRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password_hash": passwordHash,
+		"token_version": gorm.Expr("token_version + ?", 1),
+	})
 	if result.Error != nil {
-		return fmt.Errorf("failed to store verification code: %w", result.Error)
+		return fmt.Errorf("failed to update password: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return model.ErrUserNotFound
 	}
 	return nil
 }
 
-// GetVerificationCode retrieves the verification code for a user
-func (r *userRepository) GetVerificationCode(userID string) (string, error) {
-	var user model.User
-	if err := r.db.Select("verification_code").Where("id = ?", userID).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("user not found")
-		}
-		return "", fmt.Errorf("failed to get verification code: %w", err)
+// SetPendingEmail records a requested email change together with the hashed
+// confirmation code sent to the new address
+func (r *userRepository) SetPendingEmail(ctx context.Context, userID, email, codeHash string, expiresAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"pending_email":           normalizeEmail(email),
+		"email_change_code":       codeHash,
+		"email_change_expires_at": expiresAt,
+		"email_change_attempts":   0,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to store pending email: %w", result.Error)
 	}
-	return user.VerificationCode, nil
+	if result.RowsAffected == 0 {
+		return model.ErrUserNotFound
+	}
+	return nil
 }
 
-func (r *userRepository) CheckBan(userID string) (bool, error) {
-	var user model.User
-	if err := r.db.Select("is_banned").Where("id = ?", userID).First(&user).Error; err != nil {
-		return true, errors.New("check ban failed")
+// IncrementEmailChangeAttempts records a failed email change confirmation
+func (r *userRepository) IncrementEmailChangeAttempts(ctx context.Context, userID string) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
+		Update("email_change_attempts", gorm.Expr("email_change_attempts + ?", 1))
+	if result.Error != nil {
+		return fmt.Errorf("failed to record email change attempt: %w", result.Error)
 	}
-	if user.IsBanned {
-		return true, nil
+	if result.RowsAffected == 0 {
+		return model.ErrUserNotFound
 	}
-	return false, nil
+	return nil
 }
 
-func (r *userRepository) BanUser(userID string) error {
-	var user model.User
-	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+// ConfirmEmailChange swaps the pending email in as the user's email. It fails
+// with ErrDuplicateEmail if the address was registered in the meantime.
+func (r *userRepository) ConfirmEmailChange(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Where("id = ? AND pending_email <> ''", userID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrUserNotFound
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+
+		err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":                   user.PendingEmail,
+			"pending_email":           "",
+			"email_change_code":       "",
+			"email_change_expires_at": nil,
+			"email_change_attempts":   0,
+		}).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return model.ErrDuplicateEmail
+			}
+			return fmt.Errorf("failed to change email: %w", err)
+		}
+		return nil
+	})
+}
+
+// SendLimit throttles the codes sent to a user. The zero value sets no limit.
+type SendLimit struct {
+	// Cooldown is the least time between two codes
+	Cooldown time.Duration
+	// MaxSends codes may be sent until Window passes without one
+	MaxSends int
+	Window   time.Duration
+}
+
+// next returns how many codes will have been sent in the current window if
+// one more is sent at now, or false when the limit does not allow it
+func (l SendLimit) next(now time.Time, lastSent *time.Time, sends int) (int, bool) {
+	if lastSent != nil {
+		elapsed := now.Sub(*lastSent)
+		if elapsed < l.Cooldown {
+			return sends, false
+		}
+		if elapsed < l.Window {
+			if l.MaxSends > 0 && sends >= l.MaxSends {
+				return sends, false
+			}
+			return sends + 1, true
 		}
-		return fmt.Errorf("failed to find user: %w", err)
 	}
+	return 1, true
+}
+
+// StoreVerificationCode stores the hashed verification code for a user and
+// resets the attempt counter. It fails with ErrTooManyAttempts when limit
+// does not allow another code yet.
+func (r *userRepository) StoreVerificationCode(ctx context.Context, userID, codeHash string, expiresAt time.Time, limit SendLimit) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locked so concurrent resends cannot both pass the limit
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+		var user model.User
+		if err := tx.Select("verification_sent_at", "verification_sends").Where("id = ?", userID).First(&user).Error; err != nil {
+			return fmt.Errorf("failed to read verification state: %w", err)
+		}
+		now := time.Now()
+		sends, ok := limit.next(now, user.VerificationSentAt, user.VerificationSends)
+		if !ok {
+			return fmt.Errorf("%w: verification code sent too often", model.ErrTooManyAttempts)
+		}
 
-	result := r.db.Model(&user).Update("is_banned", true)
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"verification_code":       codeHash,
+			"verification_expires_at": expiresAt,
+			"verification_attempts":   0,
+			"verification_sent_at":    now,
+			"verification_sends":      sends,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to store verification code: %w", err)
+		}
+		return nil
+	})
+}
+
+// IncrementVerificationAttempts records a failed verification attempt
+func (r *userRepository) IncrementVerificationAttempts(ctx context.Context, userID string) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
+		Update("verification_attempts", gorm.Expr("verification_attempts + ?", 1))
 	if result.Error != nil {
-		return fmt.Errorf("failed to ban user: %w", result.Error)
+		return fmt.Errorf("failed to record verification attempt: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("no rows affected, user may already be banned")
+		return model.ErrUserNotFound
 	}
-
 	return nil
 }
 
-func (r *userRepository) UnBanUser(userID string) error {
-	// Check if the user exists
+// GetVerificationCode retrieves the verification code for a user
+func (r *userRepository) GetVerificationCode(ctx context.Context, userID string) (string, error) {
 	var user model.User
-	if err := r.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Select("verification_code").Where("id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
+			return "", model.ErrUserNotFound
 		}
-		return fmt.Errorf("failed to find user: %w", err)
-	}
-
-	if err := r.db.Model(&user).Updates(map[string]interface{}{"is_banned": false, "id": userID}).Error; err != nil {
-		return fmt.Errorf("failed to unban user: %w", err)
+		return "", fmt.Errorf("failed to get verification code: %w", err)
 	}
-
-	return nil
+	return user.VerificationCode, nil
 }
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	config "github.com/liju-github/FoodBuddyMicroserviceUser/configs"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/db"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestUserRepositoryContractSQLite(t *testing.T) {
+	runUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		conn, err := db.Connect(config.Config{
+			DBDriver: db.DriverSQLite,
+			DBName:   filepath.Join(t.TempDir(), "users.db"),
+		}, testLogger)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { db.Close(conn, testLogger) })
+		if err := db.MigrateUp(conn); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+		return NewUserRepository(conn)
+	})
+}
+
+// TestUserRepositoryContractMySQL runs the contract against a scratch MySQL
+// database named by TEST_MYSQL_DSN. All tables in it are dropped.
+func TestUserRepositoryContractMySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	runUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewUserRepository(openScratchDB(t, mysql.Open(dsn)))
+	})
+}
+
+// TestUserRepositoryContractPostgres runs the contract against a scratch
+// PostgreSQL database named by TEST_POSTGRES_DSN. All tables in it are dropped.
+func TestUserRepositoryContractPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	runUserRepositoryContract(t, func(t *testing.T) UserRepository {
+		return NewUserRepository(openScratchDB(t, postgres.Open(dsn)))
+	})
+}
+
+func openScratchDB(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	// Roll back whatever a previous run left behind, then rebuild the schema
+	if err := db.MigrateTo(conn, 0); err != nil {
+		t.Fatalf("failed to roll back migrations: %v", err)
+	}
+	if err := db.MigrateUp(conn); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	t.Cleanup(func() { db.Close(conn, testLogger) })
+	return conn
+}
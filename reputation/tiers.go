@@ -0,0 +1,71 @@
+// Package reputation groups reputation scores into named tiers.
+package reputation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTiers is the tier configuration used when none is set
+const DefaultTiers = "Bronze=0,Silver=100,Gold=500"
+
+// Tier is reached by users whose reputation is at least MinReputation
+type Tier struct {
+	Name          string
+	MinReputation int32
+}
+
+// Tiers is a tier configuration ordered by ascending MinReputation
+type Tiers []Tier
+
+// ParseTiers parses a list like "Bronze=0,Silver=100,Gold=500". Names and
+// thresholds must be unique; the order of the entries does not matter.
+func ParseTiers(s string) (Tiers, error) {
+	var tiers Tiers
+	names := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("malformed reputation tier %q, want name=threshold", entry)
+		}
+		threshold, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold in reputation tier %q", entry)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate reputation tier %q", name)
+		}
+		names[name] = true
+		tiers = append(tiers, Tier{Name: name, MinReputation: int32(threshold)})
+	}
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("no reputation tiers configured")
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinReputation < tiers[j].MinReputation })
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].MinReputation == tiers[i-1].MinReputation {
+			return nil, fmt.Errorf("reputation tiers %q and %q share threshold %d",
+				tiers[i-1].Name, tiers[i].Name, tiers[i].MinReputation)
+		}
+	}
+	return tiers, nil
+}
+
+// For returns the highest tier reached with reputation and the tier after it.
+// ok is false when reputation is below every threshold; next is nil at the top tier.
+func (t Tiers) For(reputation int32) (current Tier, next *Tier, ok bool) {
+	for i, tier := range t {
+		if reputation < tier.MinReputation {
+			return current, &t[i], ok
+		}
+		current, ok = tier, true
+	}
+	return current, nil, ok
+}
@@ -0,0 +1,51 @@
+package reputation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTiers(t *testing.T) {
+	tiers, err := ParseTiers(" Gold=500, Bronze=0,Silver=100 ")
+	if err != nil {
+		t.Fatalf("ParseTiers: %v", err)
+	}
+	want := Tiers{{"Bronze", 0}, {"Silver", 100}, {"Gold", 500}}
+	if !reflect.DeepEqual(tiers, want) {
+		t.Errorf("ParseTiers = %v, want %v", tiers, want)
+	}
+
+	for _, invalid := range []string{"", "Bronze", "Bronze=low", "=5", "Bronze=0,Bronze=10", "Bronze=0,Silver=0"} {
+		if _, err := ParseTiers(invalid); err == nil {
+			t.Errorf("ParseTiers(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestTiersFor(t *testing.T) {
+	tiers, _ := ParseTiers(DefaultTiers)
+	tests := []struct {
+		reputation int32
+		current    string
+		next       string
+	}{
+		{-10, "", "Bronze"},
+		{0, "Bronze", "Silver"},
+		{99, "Bronze", "Silver"},
+		{100, "Silver", "Gold"},
+		{5000, "Gold", ""},
+	}
+	for _, tt := range tests {
+		current, next, ok := tiers.For(tt.reputation)
+		if ok != (tt.current != "") || current.Name != tt.current {
+			t.Errorf("For(%d) current = %q, %v; want %q", tt.reputation, current.Name, ok, tt.current)
+		}
+		nextName := ""
+		if next != nil {
+			nextName = next.Name
+		}
+		if nextName != tt.next {
+			t.Errorf("For(%d) next = %q, want %q", tt.reputation, nextName, tt.next)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/geocoder"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/postal"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+const (
+	MaxStreetNameLength           = 255
+	MaxLocalityLength             = 255
+	MaxLandmarkLength             = 255
+	MaxFlatNumberLength           = 64
+	MaxDeliveryInstructionsLength = 500
+)
+
+var addressLabelsFromProto = map[userExtPb.AddressLabel]model.AddressLabel{
+	userExtPb.AddressLabel_ADDRESS_LABEL_UNSPECIFIED: model.AddressLabelOther,
+	userExtPb.AddressLabel_ADDRESS_LABEL_HOME:        model.AddressLabelHome,
+	userExtPb.AddressLabel_ADDRESS_LABEL_WORK:        model.AddressLabelWork,
+	userExtPb.AddressLabel_ADDRESS_LABEL_OTHER:       model.AddressLabelOther,
+}
+
+var addressLabelsToProto = map[model.AddressLabel]userExtPb.AddressLabel{
+	model.AddressLabelHome:  userExtPb.AddressLabel_ADDRESS_LABEL_HOME,
+	model.AddressLabelWork:  userExtPb.AddressLabel_ADDRESS_LABEL_WORK,
+	model.AddressLabelOther: userExtPb.AddressLabel_ADDRESS_LABEL_OTHER,
+}
+
+// addressFromProto validates an Address sent to AddAddress or EditAddress and
+// converts it, normalising the pincode and state and filling in the locality
+// and state from the pincode when they are omitted. Invalid fields are
+// reported together as a *model.ValidationError.
+func addressFromProto(pb *userPb.Address) (*model.UserAddress, error) {
+	if pb == nil {
+		return nil, &model.ValidationError{Violations: []model.FieldViolation{{Field: "address", Description: "is required"}}}
+	}
+	address := &model.UserAddress{
+		StreetName: strings.TrimSpace(pb.StreetName),
+		Locality:   pb.Locality,
+		State:      pb.State,
+		Pincode:    pb.Pincode,
+	}
+
+	var violations []model.FieldViolation
+	switch {
+	case address.StreetName == "":
+		violations = append(violations, model.FieldViolation{Field: "streetName", Description: "is required"})
+	case len(address.StreetName) > MaxStreetNameLength:
+		violations = append(violations, model.FieldViolation{
+			Field:       "streetName",
+			Description: fmt.Sprintf("must be at most %d bytes", MaxStreetNameLength),
+		})
+	}
+	violations = append(violations, postal.Normalize(address)...)
+	if len(address.Locality) > MaxLocalityLength {
+		violations = append(violations, model.FieldViolation{
+			Field:       "locality",
+			Description: fmt.Sprintf("must be at most %d bytes", MaxLocalityLength),
+		})
+	}
+
+	if len(violations) > 0 {
+		for i := range violations {
+			violations[i].Field = "address." + violations[i].Field
+		}
+		return nil, &model.ValidationError{Violations: violations}
+	}
+	return address, nil
+}
+
+// ListAddresses returns a user's addresses with their delivery details, the default first
+func (s *UserService) ListAddresses(ctx context.Context, req *userExtPb.ListAddressesRequest) (*userExtPb.ListAddressesResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	addresses, err := s.repo.GetAddresses(ctx, req.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve addresses: %w", err)
+	}
+
+	pbAddresses := make([]*userExtPb.AddressDetails, 0, len(addresses))
+	for _, address := range addresses {
+		pbAddresses = append(pbAddresses, toAddressDetails(address))
+	}
+	return &userExtPb.ListAddressesResponse{Success: true, Addresses: pbAddresses}, nil
+}
+
+// UpdateAddressDetails sets the label, landmark, flat number and delivery instructions of an address
+func (s *UserService) UpdateAddressDetails(ctx context.Context, req *userExtPb.UpdateAddressDetailsRequest) (*userExtPb.AddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	label, ok := addressLabelsFromProto[req.Label]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: unknown address label %s", model.ErrInvalidArgument, req.Label)
+	case len(req.Landmark) > MaxLandmarkLength:
+		return nil, fmt.Errorf("%w: landmark must be at most %d bytes", model.ErrInvalidArgument, MaxLandmarkLength)
+	case len(req.FlatNumber) > MaxFlatNumberLength:
+		return nil, fmt.Errorf("%w: flatNumber must be at most %d bytes", model.ErrInvalidArgument, MaxFlatNumberLength)
+	case len(req.DeliveryInstructions) > MaxDeliveryInstructionsLength:
+		return nil, fmt.Errorf("%w: deliveryInstructions must be at most %d bytes", model.ErrInvalidArgument, MaxDeliveryInstructionsLength)
+	}
+
+	details := &model.UserAddress{
+		Label:                label,
+		Landmark:             req.Landmark,
+		FlatNumber:           req.FlatNumber,
+		DeliveryInstructions: req.DeliveryInstructions,
+	}
+	if err := s.repo.UpdateAddressDetails(ctx, req.UserId, req.AddressId, details); err != nil {
+		return nil, fmt.Errorf("failed to update address details: %w", err)
+	}
+	return s.addressResponse(ctx, req.UserId, req.AddressId)
+}
+
+// SetDefaultAddress makes an address the one checkout uses by default
+func (s *UserService) SetDefaultAddress(ctx context.Context, req *userExtPb.SetDefaultAddressRequest) (*userExtPb.AddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetDefaultAddress(ctx, req.UserId, req.AddressId); err != nil {
+		return nil, fmt.Errorf("failed to set default address: %w", err)
+	}
+	return s.addressResponse(ctx, req.UserId, req.AddressId)
+}
+
+// ListAddressesByDistance returns a user's addresses ordered by distance from a point
+func (s *UserService) ListAddressesByDistance(ctx context.Context, req *userExtPb.ListAddressesByDistanceRequest) (*userExtPb.AddressesByDistanceResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	origin := geocoder.Coordinates{Latitude: req.Latitude, Longitude: req.Longitude}
+	if !origin.Valid() {
+		return nil, fmt.Errorf("%w: latitude must be within ±90 and longitude within ±180", model.ErrInvalidArgument)
+	}
+	if req.MaxDistanceMeters < 0 {
+		return nil, fmt.Errorf("%w: maxDistanceMeters must not be negative", model.ErrInvalidArgument)
+	}
+
+	addresses, err := s.repo.GetAddresses(ctx, req.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve addresses: %w", err)
+	}
+
+	var located, unlocated []*userExtPb.AddressDistance
+	for _, address := range addresses {
+		entry := &userExtPb.AddressDistance{Address: toAddressDetails(address)}
+		if address.Latitude == nil || address.Longitude == nil {
+			unlocated = append(unlocated, entry)
+			continue
+		}
+		distance := geocoder.Distance(origin, geocoder.Coordinates{Latitude: *address.Latitude, Longitude: *address.Longitude})
+		if req.MaxDistanceMeters > 0 && distance > req.MaxDistanceMeters {
+			continue
+		}
+		entry.DistanceMeters = &distance
+		located = append(located, entry)
+	}
+	sort.SliceStable(located, func(i, j int) bool { return *located[i].DistanceMeters < *located[j].DistanceMeters })
+	if req.MaxDistanceMeters == 0 {
+		located = append(located, unlocated...)
+	}
+
+	return &userExtPb.AddressesByDistanceResponse{Success: true, Addresses: located}, nil
+}
+
+// geocode fills in the coordinates of address. Geocoding is best effort, so
+// an address that cannot be located is saved without coordinates.
+func (s *UserService) geocode(ctx context.Context, address *model.UserAddress) {
+	address.Latitude, address.Longitude = nil, nil
+	coords, err := s.geocoder.Geocode(ctx, address)
+	if err != nil {
+		if !errors.Is(err, geocoder.ErrNotFound) {
+			s.logger.WarnContext(ctx, "geocoding failed", "error", err)
+		}
+		return
+	}
+	address.Latitude, address.Longitude = &coords.Latitude, &coords.Longitude
+}
+
+func (s *UserService) addressResponse(ctx context.Context, userID, addressID string) (*userExtPb.AddressResponse, error) {
+	addresses, err := s.repo.GetAddresses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve addresses: %w", err)
+	}
+	for _, address := range addresses {
+		if address.ID == addressID {
+			return &userExtPb.AddressResponse{Success: true, Address: toAddressDetails(address)}, nil
+		}
+	}
+	return nil, model.ErrAddressNotFound
+}
+
+func toAddressDetails(address *model.UserAddress) *userExtPb.AddressDetails {
+	return &userExtPb.AddressDetails{
+		AddressId:            address.ID,
+		StreetName:           address.StreetName,
+		Locality:             address.Locality,
+		State:                address.State,
+		Pincode:              address.Pincode,
+		Label:                addressLabelsToProto[address.Label],
+		IsDefault:            address.IsDefault,
+		Landmark:             address.Landmark,
+		FlatNumber:           address.FlatNumber,
+		DeliveryInstructions: address.DeliveryInstructions,
+		Latitude:             address.Latitude,
+		Longitude:            address.Longitude,
+	}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/geocoder"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+// ownerContext returns the context of a call to method made by userID
+func ownerContext(userID, method string) context.Context {
+	return callContext(&auth.Principal{UserID: userID}, method)
+}
+
+// addAddress adds an address in pincode to the user's and returns its ID
+func addAddress(t *testing.T, s *UserService, userID, pincode string) string {
+	t.Helper()
+	resp, err := s.AddAddress(ownerContext(userID, userPb.UserService_AddAddress_FullMethodName), &userPb.AddAddressRequest{
+		UserId:  userID,
+		Address: &userPb.Address{StreetName: "12 MG Road", Pincode: pincode},
+	})
+	if err != nil {
+		t.Fatalf("AddAddress in %s: %v", pincode, err)
+	}
+	return resp.AddressId
+}
+
+func listAddresses(t *testing.T, s *UserService, userID string) []*userExtPb.AddressDetails {
+	t.Helper()
+	resp, err := s.ListAddresses(ownerContext(userID, userExtPb.UserExtService_ListAddresses_FullMethodName), &userExtPb.ListAddressesRequest{UserId: userID})
+	if err != nil {
+		t.Fatalf("ListAddresses: %v", err)
+	}
+	return resp.Addresses
+}
+
+func TestDefaultAddress(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 2)
+	first := addAddress(t, s, "usr_000", "682016")
+	second := addAddress(t, s, "usr_000", "560001")
+
+	if addresses := listAddresses(t, s, "usr_000"); len(addresses) != 2 || addresses[0].AddressId != first || !addresses[0].IsDefault {
+		t.Fatalf("addresses = %v, want the first one added as default", addresses)
+	}
+
+	method := userExtPb.UserExtService_SetDefaultAddress_FullMethodName
+	resp, err := s.SetDefaultAddress(ownerContext("usr_000", method), &userExtPb.SetDefaultAddressRequest{UserId: "usr_000", AddressId: second})
+	if err != nil || !resp.Address.IsDefault {
+		t.Fatalf("SetDefaultAddress = %v, %v", resp, err)
+	}
+	addresses := listAddresses(t, s, "usr_000")
+	if addresses[0].AddressId != second || addresses[1].IsDefault {
+		t.Errorf("addresses after SetDefaultAddress = %v, want only the second as default and first", addresses)
+	}
+
+	_, err = s.SetDefaultAddress(ownerContext("usr_000", method), &userExtPb.SetDefaultAddressRequest{UserId: "usr_000", AddressId: "addr_missing"})
+	if !errors.Is(err, model.ErrAddressNotFound) {
+		t.Errorf("SetDefaultAddress of a missing address = %v, want ErrAddressNotFound", err)
+	}
+	_, err = s.SetDefaultAddress(ownerContext("usr_001", method), &userExtPb.SetDefaultAddressRequest{UserId: "usr_000", AddressId: first})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("SetDefaultAddress on another user's address = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestUpdateAddressDetails(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 1)
+	addressID := addAddress(t, s, "usr_000", "682016")
+	ctx := ownerContext("usr_000", userExtPb.UserExtService_UpdateAddressDetails_FullMethodName)
+
+	if details := listAddresses(t, s, "usr_000")[0]; details.Label != userExtPb.AddressLabel_ADDRESS_LABEL_OTHER {
+		t.Errorf("label of a new address = %v, want OTHER", details.Label)
+	}
+
+	resp, err := s.UpdateAddressDetails(ctx, &userExtPb.UpdateAddressDetailsRequest{
+		UserId:               "usr_000",
+		AddressId:            addressID,
+		Label:                userExtPb.AddressLabel_ADDRESS_LABEL_HOME,
+		Landmark:             "Opposite the metro station",
+		FlatNumber:           "4B",
+		DeliveryInstructions: "Ring twice",
+	})
+	if err != nil {
+		t.Fatalf("UpdateAddressDetails: %v", err)
+	}
+	if a := resp.Address; a.Label != userExtPb.AddressLabel_ADDRESS_LABEL_HOME || a.Landmark != "Opposite the metro station" ||
+		a.FlatNumber != "4B" || a.DeliveryInstructions != "Ring twice" || a.Pincode != "682016" {
+		t.Errorf("updated address = %v", a)
+	}
+
+	for name, req := range map[string]*userExtPb.UpdateAddressDetailsRequest{
+		"unknown label":     {Label: userExtPb.AddressLabel(99)},
+		"long landmark":     {Landmark: strings.Repeat("x", MaxLandmarkLength+1)},
+		"long flat number":  {FlatNumber: strings.Repeat("x", MaxFlatNumberLength+1)},
+		"long instructions": {DeliveryInstructions: strings.Repeat("x", MaxDeliveryInstructionsLength+1)},
+	} {
+		req.UserId, req.AddressId = "usr_000", addressID
+		if _, err := s.UpdateAddressDetails(ctx, req); !errors.Is(err, model.ErrInvalidArgument) {
+			t.Errorf("%s: err = %v, want ErrInvalidArgument", name, err)
+		}
+	}
+	_, err = s.UpdateAddressDetails(ctx, &userExtPb.UpdateAddressDetailsRequest{UserId: "usr_000", AddressId: "addr_missing"})
+	if !errors.Is(err, model.ErrAddressNotFound) {
+		t.Errorf("UpdateAddressDetails of a missing address = %v, want ErrAddressNotFound", err)
+	}
+}
+
+// failingGeocoder stands for a geocoding service that is down
+type failingGeocoder struct{}
+
+func (failingGeocoder) Geocode(ctx context.Context, address *model.UserAddress) (geocoder.Coordinates, error) {
+	return geocoder.Coordinates{}, errors.New("geocoder unavailable")
+}
+
+func TestAddressGeocoding(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 1)
+	fixture, err := geocoder.NewFixtureGeocoder()
+	if err != nil {
+		t.Fatalf("NewFixtureGeocoder: %v", err)
+	}
+	s.geocoder = fixture
+
+	located := addAddress(t, s, "usr_000", "682016")
+	// Known to the postal directory but not to the geocoder
+	unlocated := addAddress(t, s, "usr_000", "695014")
+	s.geocoder = failingGeocoder{}
+	failed := addAddress(t, s, "usr_000", "560001")
+
+	coordinates := map[string]*userExtPb.AddressDetails{}
+	for _, address := range listAddresses(t, s, "usr_000") {
+		coordinates[address.AddressId] = address
+	}
+	if a := coordinates[located]; a.Latitude == nil || *a.Latitude != 9.97 || *a.Longitude != 76.29 {
+		t.Errorf("geocoded address = %v, want the centre of 682016", a)
+	}
+	for _, id := range []string{unlocated, failed} {
+		if a := coordinates[id]; a.Latitude != nil || a.Longitude != nil {
+			t.Errorf("address %s was saved with coordinates", id)
+		}
+	}
+
+	// Editing locates the address again
+	s.geocoder = fixture
+	_, err = s.EditAddress(ownerContext("usr_000", userPb.UserService_EditAddress_FullMethodName), &userPb.EditAddressRequest{
+		UserId:    "usr_000",
+		AddressId: located,
+		Address:   &userPb.Address{StreetName: "1 Marine Drive", Pincode: "400001"},
+	})
+	if err != nil {
+		t.Fatalf("EditAddress: %v", err)
+	}
+	if a := listAddresses(t, s, "usr_000")[0]; a.AddressId != located || a.Latitude == nil || *a.Latitude != 18.9388 {
+		t.Errorf("edited address = %v, want the centre of 400001", a)
+	}
+}
+
+func TestListAddressesByDistance(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 1)
+	fixture, err := geocoder.NewFixtureGeocoder()
+	if err != nil {
+		t.Fatalf("NewFixtureGeocoder: %v", err)
+	}
+	s.geocoder = fixture
+	bangalore := addAddress(t, s, "usr_000", "560001")
+	unlocated := addAddress(t, s, "usr_000", "695014")
+	kochi := addAddress(t, s, "usr_000", "682016")
+	ctx := ownerContext("usr_000", userExtPb.UserExtService_ListAddressesByDistance_FullMethodName)
+
+	ids := func(req *userExtPb.ListAddressesByDistanceRequest) []string {
+		t.Helper()
+		req.UserId = "usr_000"
+		resp, err := s.ListAddressesByDistance(ctx, req)
+		if err != nil {
+			t.Fatalf("ListAddressesByDistance: %v", err)
+		}
+		var ids []string
+		for _, entry := range resp.Addresses {
+			ids = append(ids, entry.Address.AddressId)
+		}
+		return ids
+	}
+
+	// Addresses without coordinates come last, and only without a radius
+	near := &userExtPb.ListAddressesByDistanceRequest{Latitude: 9.98, Longitude: 76.28}
+	if got, want := ids(near), []string{kochi, bangalore, unlocated}; !reflect.DeepEqual(got, want) {
+		t.Errorf("addresses by distance from Kochi = %v, want %v", got, want)
+	}
+	near.MaxDistanceMeters = 10000
+	if got, want := ids(near), []string{kochi}; !reflect.DeepEqual(got, want) {
+		t.Errorf("addresses within 10 km of Kochi = %v, want %v", got, want)
+	}
+
+	for _, req := range []*userExtPb.ListAddressesByDistanceRequest{
+		{UserId: "usr_000", Latitude: 91},
+		{UserId: "usr_000", Longitude: -181},
+		{UserId: "usr_000", MaxDistanceMeters: -1},
+	} {
+		if _, err := s.ListAddressesByDistance(ctx, req); !errors.Is(err, model.ErrInvalidArgument) {
+			t.Errorf("ListAddressesByDistance(%v) = %v, want ErrInvalidArgument", req, err)
+		}
+	}
+}
+
+func TestAddAddressValidation(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 1)
+	ctx := ownerContext("usr_000", userPb.UserService_AddAddress_FullMethodName)
+
+	// The state and locality are filled in from the pincode, and the state
+	// is written in canonical form
+	for _, address := range []*userPb.Address{
+		{StreetName: " 12 MG Road ", Pincode: "682 016"},
+		{StreetName: "12 MG Road", Pincode: "682016", State: "kerala", Locality: "Ernakulam South"},
+	} {
+		if _, err := s.AddAddress(ctx, &userPb.AddAddressRequest{UserId: "usr_000", Address: address}); err != nil {
+			t.Fatalf("AddAddress(%v): %v", address, err)
+		}
+	}
+	for _, a := range listAddresses(t, s, "usr_000") {
+		if a.StreetName != "12 MG Road" || a.Pincode != "682016" || a.State != "Kerala" || a.Locality != "Ernakulam South" {
+			t.Errorf("stored address = %v", a)
+		}
+	}
+
+	tests := []struct {
+		address *userPb.Address
+		fields  []string
+	}{
+		{nil, []string{"address"}},
+		{&userPb.Address{Pincode: "682016"}, []string{"address.streetName"}},
+		{&userPb.Address{StreetName: "12 MG Road", Pincode: "068201"}, []string{"address.pincode"}},
+		{&userPb.Address{StreetName: "12 MG Road", Pincode: "999999"}, []string{"address.pincode"}},
+		{&userPb.Address{StreetName: "12 MG Road", Pincode: "682016", State: "Goa"}, []string{"address.state"}},
+		{&userPb.Address{State: "Atlantis"}, []string{"address.streetName", "address.state", "address.pincode"}},
+	}
+	for _, tt := range tests {
+		_, err := s.AddAddress(ctx, &userPb.AddAddressRequest{UserId: "usr_000", Address: tt.address})
+		var validation *model.ValidationError
+		if !errors.As(err, &validation) {
+			t.Errorf("AddAddress(%v) = %v, want a ValidationError", tt.address, err)
+			continue
+		}
+		var fields []string
+		for _, v := range validation.Violations {
+			fields = append(fields, v.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("AddAddress(%v) violations on %v, want %v", tt.address, fields, tt.fields)
+		}
+	}
+	if n := len(listAddresses(t, s, "usr_000")); n != 2 {
+		t.Errorf("%d addresses stored, want only the 2 valid ones", n)
+	}
+}
+
+func TestAddAddressLimit(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 2)
+	s.maxAddresses = 2
+
+	first := addAddress(t, s, "usr_000", "682016")
+	second := addAddress(t, s, "usr_000", "682016")
+	if first == second || !strings.HasPrefix(first, "addr_") {
+		t.Errorf("address IDs %q and %q", first, second)
+	}
+
+	ctx := ownerContext("usr_000", userPb.UserService_AddAddress_FullMethodName)
+	request := &userPb.AddAddressRequest{UserId: "usr_000", Address: &userPb.Address{StreetName: "12 MG Road", Pincode: "682016"}}
+	if _, err := s.AddAddress(ctx, request); !errors.Is(err, model.ErrAddressLimit) {
+		t.Fatalf("AddAddress over the limit = %v, want ErrAddressLimit", err)
+	}
+	// The limit is per user
+	addAddress(t, s, "usr_001", "682016")
+
+	_, err := s.DeleteAddress(ownerContext("usr_000", userPb.UserService_DeleteAddress_FullMethodName), &userPb.DeleteAddressRequest{UserId: "usr_000", AddressId: first})
+	if err != nil {
+		t.Fatalf("DeleteAddress: %v", err)
+	}
+	if _, err := s.AddAddress(ctx, request); err != nil {
+		t.Errorf("AddAddress after deleting one: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// PublicMethods lists the RPCs that may be called without a bearer token.
+// They either establish a session or carry their own credential in the request.
+var PublicMethods = map[string]bool{
+	userPb.UserService_UserSignup_FullMethodName:                 true,
+	userPb.UserService_UserLogin_FullMethodName:                  true,
+	userPb.UserService_VerifyEmail_FullMethodName:                true,
+	userPb.UserService_GetUserByToken_FullMethodName:             true,
+	userExtPb.UserExtService_ValidateToken_FullMethodName:        true,
+	userExtPb.UserExtService_CreateSession_FullMethodName:        true,
+	userExtPb.UserExtService_RefreshToken_FullMethodName:         true,
+	userExtPb.UserExtService_Logout_FullMethodName:               true,
+	userExtPb.UserExtService_ResendVerification_FullMethodName:   true,
+	userExtPb.UserExtService_RequestPasswordReset_FullMethodName: true,
+	userExtPb.UserExtService_ConfirmPasswordReset_FullMethodName: true,
+	healthpb.Health_Check_FullMethodName:                         true,
+	healthpb.Health_Watch_FullMethodName:                         true,
+}
+
+// methodPermissions is the permission table consulted by the handlers. For
+// RPCs acting on a given user it is the permission needed to act on someone
+// else's account; callers may always act on their own. RPCs missing from the
+// table are restricted to the account owner, or denied when they have no owner
+// unless, like GetLeaderboard, they are open to every signed-in user.
+var methodPermissions = map[string]auth.Permission{
+	userPb.UserService_GetProfile_FullMethodName:                    auth.PermissionReadUsers,
+	userPb.UserService_CheckBan_FullMethodName:                      auth.PermissionCheckUsers,
+	userPb.UserService_GetAddresses_FullMethodName:                  auth.PermissionReadUsers,
+	userPb.UserService_ValidateUserAddress_FullMethodName:           auth.PermissionCheckUsers,
+	userExtPb.UserExtService_ListAddresses_FullMethodName:           auth.PermissionReadUsers,
+	userExtPb.UserExtService_ListAddressesByDistance_FullMethodName: auth.PermissionReadUsers,
+	userPb.UserService_GetAllUsers_FullMethodName:                   auth.PermissionReadUsers,
+	userExtPb.UserExtService_ListUsers_FullMethodName:               auth.PermissionReadUsers,
+	userExtPb.UserExtService_ExportUsers_FullMethodName:             auth.PermissionReadUsers,
+	userExtPb.UserExtService_GetUserRoles_FullMethodName:            auth.PermissionReadUsers,
+	userExtPb.UserExtService_GetReputationSummary_FullMethodName:    auth.PermissionReadUsers,
+	userPb.UserService_UpdateProfile_FullMethodName:                 auth.PermissionManageUsers,
+	userPb.UserService_AddAddress_FullMethodName:                    auth.PermissionManageUsers,
+	userPb.UserService_EditAddress_FullMethodName:                   auth.PermissionManageUsers,
+	userPb.UserService_DeleteAddress_FullMethodName:                 auth.PermissionManageUsers,
+	userExtPb.UserExtService_UpdateAddressDetails_FullMethodName:    auth.PermissionManageUsers,
+	userExtPb.UserExtService_SetDefaultAddress_FullMethodName:       auth.PermissionManageUsers,
+	userExtPb.UserExtService_LogoutAllDevices_FullMethodName:        auth.PermissionManageUsers,
+	userPb.UserService_BanUser_FullMethodName:                       auth.PermissionBanUsers,
+	userPb.UserService_UnBanUser_FullMethodName:                     auth.PermissionBanUsers,
+	userExtPb.UserExtService_BanUserWithReason_FullMethodName:       auth.PermissionBanUsers,
+	userExtPb.UserExtService_GetBanHistory_FullMethodName:           auth.PermissionBanUsers,
+	userExtPb.UserExtService_AdjustReputation_FullMethodName:        auth.PermissionAdjustReputation,
+	userExtPb.UserExtService_GrantRole_FullMethodName:               auth.PermissionManageRoles,
+	userExtPb.UserExtService_RevokeRole_FullMethodName:              auth.PermissionManageRoles,
+}
+
+// authorizeUser allows the caller to act on userID when it is their own
+// account or when their roles grant the permission of the current RPC
+func authorizeUser(ctx context.Context, userID string) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: unauthenticated caller", model.ErrInvalidToken)
+	}
+	if principal.UserID == userID {
+		return nil
+	}
+	return authorize(ctx)
+}
+
+// authorize allows the caller to continue when their roles grant the
+// permission of the current RPC
+func authorize(ctx context.Context) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: unauthenticated caller", model.ErrInvalidToken)
+	}
+
+	method, _ := grpc.Method(ctx)
+	perm, ok := methodPermissions[method]
+	if !ok || !principal.Can(perm) {
+		return fmt.Errorf("%w: %s requires more privileges", model.ErrPermissionDenied, path.Base(method))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+func TestAuthorizeUser(t *testing.T) {
+	const (
+		getProfile      = userPb.UserService_GetProfile_FullMethodName
+		checkBan        = userPb.UserService_CheckBan_FullMethodName
+		validateAddress = userPb.UserService_ValidateUserAddress_FullMethodName
+		updateProfile   = userPb.UserService_UpdateProfile_FullMethodName
+		changePassword  = userExtPb.UserExtService_ChangePassword_FullMethodName
+	)
+	tests := []struct {
+		role   model.Role
+		method string
+		target string
+		want   error
+	}{
+		// Everyone may act on their own account
+		{model.RoleUser, getProfile, "usr_caller", nil},
+		{model.RoleUser, changePassword, "usr_caller", nil},
+		{model.RoleService, updateProfile, "usr_caller", nil},
+
+		{model.RoleUser, getProfile, "usr_other", model.ErrPermissionDenied},
+		{model.RoleUser, checkBan, "usr_other", model.ErrPermissionDenied},
+		{model.RoleSupport, getProfile, "usr_other", nil},
+		{model.RoleSupport, updateProfile, "usr_other", nil},
+		{model.RoleModerator, checkBan, "usr_other", nil},
+		{model.RoleModerator, updateProfile, "usr_other", model.ErrPermissionDenied},
+		{model.RoleService, checkBan, "usr_other", nil},
+		{model.RoleService, validateAddress, "usr_other", nil},
+		{model.RoleService, getProfile, "usr_other", model.ErrPermissionDenied},
+		{model.RoleAdmin, updateProfile, "usr_other", nil},
+
+		// Methods without a permission are only open to the owner
+		{model.RoleAdmin, changePassword, "usr_other", model.ErrPermissionDenied},
+	}
+	for _, tt := range tests {
+		principal := &auth.Principal{UserID: "usr_caller", Roles: []model.Role{tt.role}}
+		err := authorizeUser(callContext(principal, tt.method), tt.target)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s calling %s on %s = %v, want %v", tt.role, tt.method, tt.target, err, tt.want)
+		}
+	}
+
+	if err := authorizeUser(context.Background(), "usr_caller"); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("unauthenticated caller = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		role   model.Role
+		method string
+		want   error
+	}{
+		{model.RoleUser, userPb.UserService_BanUser_FullMethodName, model.ErrPermissionDenied},
+		{model.RoleSupport, userPb.UserService_BanUser_FullMethodName, model.ErrPermissionDenied},
+		{model.RoleModerator, userPb.UserService_BanUser_FullMethodName, nil},
+		{model.RoleModerator, userExtPb.UserExtService_GrantRole_FullMethodName, model.ErrPermissionDenied},
+		{model.RoleAdmin, userExtPb.UserExtService_GrantRole_FullMethodName, nil},
+		{model.RoleService, userExtPb.UserExtService_AdjustReputation_FullMethodName, nil},
+		{model.RoleService, userExtPb.UserExtService_ListUsers_FullMethodName, model.ErrPermissionDenied},
+		{model.RoleSupport, userExtPb.UserExtService_ListUsers_FullMethodName, nil},
+	}
+	for _, tt := range tests {
+		principal := &auth.Principal{UserID: "usr_caller", Roles: []model.Role{tt.role}}
+		err := authorize(callContext(principal, tt.method))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s calling %s = %v, want %v", tt.role, tt.method, err, tt.want)
+		}
+	}
+}
+
+func TestPublicMethodsNeedNoPermission(t *testing.T) {
+	for method := range PublicMethods {
+		if perm, ok := methodPermissions[method]; ok {
+			t.Errorf("public method %s requires %s", method, perm)
+		}
+	}
+}
+
+func TestCheckBanByService(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	ctx := context.Background()
+	userID, _ := signUpVerified(t, s, mailer, "alice@example.com")
+	serviceID, _ := signUpVerified(t, s, mailer, "orders@example.com")
+	login, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "orders@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("UserLogin: %v", err)
+	}
+	// Roles are loaded on each call, so the token from before the grant carries them
+	if err := repo.GrantRole(ctx, &model.UserRole{UserID: serviceID, Role: model.RoleService}); err != nil {
+		t.Fatalf("GrantRole: %v", err)
+	}
+
+	resp, err := s.CheckBan(tokenContext(t, s, login.Token, userPb.UserService_CheckBan_FullMethodName), &userPb.CheckBanRequest{UserId: userID})
+	if err != nil || resp.BanStatus {
+		t.Errorf("CheckBan by a service = %v, %v", resp.GetBanStatus(), err)
+	}
+	_, err = s.GetProfile(tokenContext(t, s, login.Token, userPb.UserService_GetProfile_FullMethodName), &userPb.GetProfileRequest{UserId: userID})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("GetProfile by a service = %v, want ErrPermissionDenied", err)
+	}
+}
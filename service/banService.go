@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/metrics"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+// MaxBanReasonLength matches the size of the user_bans.reason column
+const MaxBanReasonLength = 1024
+
+// BanUserWithReason bans a user with a reason, permanently or for a duration
+func (s *UserService) BanUserWithReason(ctx context.Context, req *userExtPb.BanUserWithReasonRequest) (*userExtPb.BanResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.UserId == "" {
+		return nil, fmt.Errorf("%w: userId is required", model.ErrInvalidArgument)
+	}
+	if len(req.Reason) > MaxBanReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d bytes", model.ErrInvalidArgument, MaxBanReasonLength)
+	}
+	if req.DurationSeconds < 0 {
+		return nil, fmt.Errorf("%w: durationSeconds must not be negative", model.ErrInvalidArgument)
+	}
+
+	ban, err := s.banUser(ctx, req.UserId, req.Reason, time.Duration(req.DurationSeconds)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &userExtPb.BanResponse{Success: true, Ban: banToProto(ban, time.Now())}, nil
+}
+
+// GetBanHistory lists every ban of a user, newest first
+func (s *UserService) GetBanHistory(ctx context.Context, req *userExtPb.GetBanHistoryRequest) (*userExtPb.BanHistoryResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	bans, err := s.repo.GetBanHistory(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pbBans := make([]*userExtPb.UserBan, 0, len(bans))
+	for _, ban := range bans {
+		pbBans = append(pbBans, banToProto(ban, now))
+	}
+	return &userExtPb.BanHistoryResponse{Success: true, UserId: req.UserId, Bans: pbBans}, nil
+}
+
+// banUser records a ban by the caller. A zero duration bans permanently.
+// Since banned users are locked out, only admins may ban a user holding a
+// role the caller lacks, so a moderator cannot lock out admins or services.
+func (s *UserService) banUser(ctx context.Context, userID, reason string, duration time.Duration) (*model.UserBan, error) {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if !principal.HasRole(model.RoleAdmin) {
+		roles, err := s.repo.GetUserRoles(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			if !principal.HasRole(role) {
+				return nil, fmt.Errorf("%w: only admins may ban a user with the %s role", model.ErrPermissionDenied, role)
+			}
+		}
+	}
+
+	ban := &model.UserBan{
+		ID:       fmt.Sprintf("ban_%s", uuid.New().String()),
+		UserID:   userID,
+		Reason:   reason,
+		BannedBy: principal.UserID,
+	}
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.BanUser(ctx, ban); err != nil {
+		return nil, err
+	}
+	metrics.Bans.Inc()
+	s.logger.InfoContext(ctx, "user banned", "user_id", userID, "banned_by", principal.UserID, "duration", duration)
+	return ban, nil
+}
+
+// RunBanSweeper lifts lapsed temporary bans every interval until ctx is
+// cancelled. CheckBan already ignores them; this keeps the cached IsBanned
+// flag seen by profiles and user listings current.
+func (s *UserService) RunBanSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lifted, err := s.repo.LiftExpiredBans(ctx, time.Now())
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to lift expired bans", "error", err)
+				continue
+			}
+			if lifted > 0 {
+				s.logger.InfoContext(ctx, "lifted expired bans", "count", lifted)
+			}
+		}
+	}
+}
+
+// checkNotBanned fails with ErrUserBanned while the user has an active ban.
+// IsBanned stays set until an expired ban is lifted, which CheckBan does.
+func (s *UserService) checkNotBanned(ctx context.Context, user *model.User) error {
+	if !user.IsBanned {
+		return nil
+	}
+	banned, err := s.repo.CheckBan(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return model.ErrUserBanned
+	}
+	user.IsBanned = false
+	return nil
+}
+
+func banToProto(ban *model.UserBan, now time.Time) *userExtPb.UserBan {
+	pbBan := &userExtPb.UserBan{
+		BanId:     ban.ID,
+		UserId:    ban.UserID,
+		Reason:    ban.Reason,
+		BannedBy:  ban.BannedBy,
+		CreatedAt: ban.CreatedAt.Unix(),
+		LiftedBy:  ban.LiftedBy,
+		Active:    ban.Active(now),
+	}
+	if ban.ExpiresAt != nil {
+		pbBan.ExpiresAt = ban.ExpiresAt.Unix()
+	}
+	if ban.LiftedAt != nil {
+		pbBan.LiftedAt = ban.LiftedAt.Unix()
+	}
+	return pbBan
+}
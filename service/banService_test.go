@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+func TestCheckBanLiftsExpiredBan(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	ctx := context.Background()
+	adminID, _ := signUpVerified(t, s, mailer, "admin@example.com")
+	admin := callContext(&auth.Principal{UserID: adminID, Roles: []model.Role{model.RoleAdmin}}, userPb.UserService_CheckBan_FullMethodName)
+
+	bannedID, _ := signUpVerified(t, s, mailer, "banned@example.com")
+	if err := repo.BanUser(ctx, &model.UserBan{ID: "ban_1", UserID: bannedID, BannedBy: adminID}); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	lapsedID, _ := signUpVerified(t, s, mailer, "lapsed@example.com")
+	expiredAt := time.Now().Add(-time.Minute)
+	if err := repo.BanUser(ctx, &model.UserBan{ID: "ban_2", UserID: lapsedID, BannedBy: adminID, ExpiresAt: &expiredAt}); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+
+	resp, err := s.CheckBan(admin, &userPb.CheckBanRequest{UserId: bannedID})
+	if err != nil || !resp.BanStatus {
+		t.Errorf("CheckBan of a banned user = %v, %v, want true", resp.GetBanStatus(), err)
+	}
+	if _, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "banned@example.com", Password: testPassword}); !errors.Is(err, model.ErrUserBanned) {
+		t.Errorf("UserLogin of a banned user = %v, want ErrUserBanned", err)
+	}
+
+	resp, err = s.CheckBan(admin, &userPb.CheckBanRequest{UserId: lapsedID})
+	if err != nil || resp.BanStatus {
+		t.Errorf("CheckBan after the ban expired = %v, %v, want false", resp.GetBanStatus(), err)
+	}
+	if _, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "lapsed@example.com", Password: testPassword}); err != nil {
+		t.Errorf("UserLogin after the ban expired: %v", err)
+	}
+}
+
+func TestBanUserProtectsPrivilegedRoles(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	ctx := context.Background()
+	targets := map[model.Role]string{}
+	for _, role := range []model.Role{model.RoleUser, model.RoleModerator, model.RoleAdmin, model.RoleService} {
+		userID, _ := signUpVerified(t, s, mailer, string(role)+"@example.com")
+		if role != model.RoleUser {
+			if err := repo.GrantRole(ctx, &model.UserRole{UserID: userID, Role: role}); err != nil {
+				t.Fatalf("GrantRole: %v", err)
+			}
+		}
+		targets[role] = userID
+	}
+	method := userExtPb.UserExtService_BanUserWithReason_FullMethodName
+	moderator := callContext(&auth.Principal{UserID: "usr_moderator", Roles: []model.Role{model.RoleModerator}}, method)
+	admin := callContext(&auth.Principal{UserID: "usr_admin", Roles: []model.Role{model.RoleAdmin}}, method)
+
+	for _, role := range []model.Role{model.RoleAdmin, model.RoleService} {
+		_, err := s.BanUserWithReason(moderator, &userExtPb.BanUserWithReasonRequest{UserId: targets[role], Reason: "spam"})
+		if !errors.Is(err, model.ErrPermissionDenied) {
+			t.Errorf("moderator banning a user with the %s role = %v, want ErrPermissionDenied", role, err)
+		}
+		if banned, _ := repo.CheckBan(ctx, targets[role]); banned {
+			t.Errorf("user with the %s role was banned by a moderator", role)
+		}
+	}
+
+	for _, role := range []model.Role{model.RoleUser, model.RoleModerator} {
+		if _, err := s.BanUserWithReason(moderator, &userExtPb.BanUserWithReasonRequest{UserId: targets[role], Reason: "spam"}); err != nil {
+			t.Errorf("moderator banning a user with the %s role: %v", role, err)
+		}
+	}
+	if _, err := s.BanUserWithReason(admin, &userExtPb.BanUserWithReasonRequest{UserId: targets[model.RoleService], Reason: "compromised"}); err != nil {
+		t.Errorf("admin banning a service: %v", err)
+	}
+}
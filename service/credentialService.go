@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+// ChangePassword replaces the password of a user who knows their current one
+// and signs them out of every device, revoking the tokens issued before
+func (s *UserService) ChangePassword(ctx context.Context, req *userExtPb.ChangePasswordRequest) (*userExtPb.ChangePasswordResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return nil, model.ErrInvalidPassword
+	}
+
+	passwordHash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+	if err := s.repo.RevokeUserSessions(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return &userExtPb.ChangePasswordResponse{
+		Success: true,
+		Message: "Password changed successfully. Please log in again.",
+	}, nil
+}
+
+// RequestEmailChange starts an email change by sending a confirmation code to
+// the new address. The email is only swapped by ConfirmEmailChange.
+func (s *UserService) RequestEmailChange(ctx context.Context, req *userExtPb.RequestEmailChangeRequest) (*userExtPb.ChangeEmailResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if newEmail == "" {
+		return nil, fmt.Errorf("%w: new email is required", model.ErrInvalidArgument)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return nil, model.ErrInvalidPassword
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, fmt.Errorf("%w: new email must differ from the current email", model.ErrInvalidArgument)
+	}
+
+	if existing, err := s.repo.GetUserByEmail(ctx, newEmail); err == nil && existing != nil {
+		return nil, model.ErrDuplicateEmail
+	}
+
+	code, codeHash, err := auth.NewVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPendingEmail(ctx, user.ID, newEmail, codeHash, time.Now().Add(EmailChangeCodeExpiry)); err != nil {
+		return nil, fmt.Errorf("failed to request email change: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, notification.Message{
+		To:      newEmail,
+		Subject: "Confirm your new FoodBuddy email",
+		Body: fmt.Sprintf("Your FoodBuddy email change code is %s. It expires in %d minutes.",
+			code, int(EmailChangeCodeExpiry.Minutes())),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	return &userExtPb.ChangeEmailResponse{
+		Success: true,
+		Message: "A confirmation code has been sent to the new email address",
+		Email:   user.Email,
+	}, nil
+}
+
+// ConfirmEmailChange verifies the code sent to the pending address and makes it the user's email
+func (s *UserService) ConfirmEmailChange(ctx context.Context, req *userExtPb.ConfirmEmailChangeRequest) (*userExtPb.ChangeEmailResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PendingEmail == "" || user.EmailChangeExpiresAt == nil || time.Now().After(*user.EmailChangeExpiresAt) {
+		return nil, model.ErrCodeExpired
+	}
+
+	if user.EmailChangeAttempts >= MaxVerificationAttempts {
+		return nil, model.ErrTooManyAttempts
+	}
+
+	if !auth.CodeMatches(req.VerificationCode, user.EmailChangeCode) {
+		if err := s.repo.IncrementEmailChangeAttempts(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to record email change attempt: %w", err)
+		}
+		return nil, model.ErrInvalidCode
+	}
+
+	if err := s.repo.ConfirmEmailChange(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// Let the previous address know in case the change was not made by its owner
+	err = s.mailer.Send(ctx, notification.Message{
+		To:      user.Email,
+		Subject: "Your FoodBuddy email was changed",
+		Body:    "The email address on your FoodBuddy account has been changed. If this wasn't you, contact support immediately.",
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to send email change notice", "user_id", user.ID, "error", err)
+	}
+
+	return &userExtPb.ChangeEmailResponse{
+		Success: true,
+		Message: "Email changed successfully",
+		Email:   user.PendingEmail,
+	}, nil
+}
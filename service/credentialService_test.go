@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+func TestChangePassword(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	userID, token := signUpVerified(t, s, mailer, "alice@example.com")
+	otherID, otherToken := signUpVerified(t, s, mailer, "bob@example.com")
+	method := userExtPb.UserExtService_ChangePassword_FullMethodName
+	session, err := s.CreateSession(ctx, &userExtPb.CreateSessionRequest{Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	_, err = s.ChangePassword(tokenContext(t, s, otherToken, method), &userExtPb.ChangePasswordRequest{UserId: userID, CurrentPassword: testPassword, NewPassword: "new-password"})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("ChangePassword of another user = %v, want ErrPermissionDenied", err)
+	}
+	_, err = s.ChangePassword(tokenContext(t, s, token, method), &userExtPb.ChangePasswordRequest{UserId: userID, CurrentPassword: "wrong-password", NewPassword: "new-password"})
+	if !errors.Is(err, model.ErrInvalidPassword) {
+		t.Errorf("ChangePassword with a wrong current password = %v, want ErrInvalidPassword", err)
+	}
+	_, err = s.ChangePassword(tokenContext(t, s, token, method), &userExtPb.ChangePasswordRequest{UserId: userID, CurrentPassword: testPassword, NewPassword: "short"})
+	if !errors.Is(err, model.ErrWeakPassword) {
+		t.Errorf("ChangePassword to a weak password = %v, want ErrWeakPassword", err)
+	}
+
+	ctx = tokenContext(t, s, token, method)
+	if _, err := s.ChangePassword(ctx, &userExtPb.ChangePasswordRequest{UserId: userID, CurrentPassword: testPassword, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	// Every device is signed out, and other accounts are untouched
+	if _, err := s.Authenticate(ctx, token); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("access token issued before the change = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: session.RefreshToken}); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("refresh token issued before the change = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "alice@example.com", Password: "new-password"}); err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+	if principal, err := s.Authenticate(ctx, otherToken); err != nil || principal.UserID != otherID {
+		t.Errorf("token of another user = %v, %v", principal, err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	userID, token := signUpVerified(t, s, mailer, "alice@example.com")
+	signUpVerified(t, s, mailer, "bob@example.com")
+	request := tokenContext(t, s, token, userExtPb.UserExtService_RequestEmailChange_FullMethodName)
+	confirm := tokenContext(t, s, token, userExtPb.UserExtService_ConfirmEmailChange_FullMethodName)
+
+	_, err := s.RequestEmailChange(request, &userExtPb.RequestEmailChangeRequest{UserId: userID, NewEmail: "bob@example.com", CurrentPassword: testPassword})
+	if !errors.Is(err, model.ErrDuplicateEmail) {
+		t.Errorf("RequestEmailChange to a registered email = %v, want ErrDuplicateEmail", err)
+	}
+
+	// The new address is taken by a signup while the change is pending
+	if _, err := s.RequestEmailChange(request, &userExtPb.RequestEmailChangeRequest{UserId: userID, NewEmail: "carol@example.com", CurrentPassword: testPassword}); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	code := mailer.lastCode(t, "carol@example.com")
+	signUp(t, s, "carol@example.com")
+	_, err = s.ConfirmEmailChange(confirm, &userExtPb.ConfirmEmailChangeRequest{UserId: userID, VerificationCode: code})
+	if !errors.Is(err, model.ErrDuplicateEmail) {
+		t.Errorf("ConfirmEmailChange to an email registered meanwhile = %v, want ErrDuplicateEmail", err)
+	}
+
+	if _, err := s.RequestEmailChange(request, &userExtPb.RequestEmailChangeRequest{UserId: userID, NewEmail: "alice@example.org", CurrentPassword: testPassword}); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	notices := len(mailer.sent("alice@example.com"))
+	resp, err := s.ConfirmEmailChange(confirm, &userExtPb.ConfirmEmailChangeRequest{UserId: userID, VerificationCode: mailer.lastCode(t, "alice@example.org")})
+	if err != nil || resp.Email != "alice@example.org" {
+		t.Fatalf("ConfirmEmailChange = %v, %v", resp, err)
+	}
+	user, _ := repo.GetUserByID(context.Background(), userID)
+	if user.Email != "alice@example.org" || user.PendingEmail != "" {
+		t.Errorf("email not changed: %+v", user)
+	}
+	if len(mailer.sent("alice@example.com")) != notices+1 {
+		t.Error("previous address was not notified")
+	}
+}
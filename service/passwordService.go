@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+// RequestPasswordReset emails a single-use reset token to the account owner.
+// The response never reveals whether the email is registered, nor whether the
+// request was throttled.
+func (s *UserService) RequestPasswordReset(ctx context.Context, req *userExtPb.RequestPasswordResetRequest) (*userExtPb.PasswordResetResponse, error) {
+	response := &userExtPb.PasswordResetResponse{
+		Success: true,
+		Message: "If an account exists for this email, password reset instructions have been sent.",
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return response, nil
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	resetToken := &model.PasswordResetToken{
+		ID:        fmt.Sprintf("prt_%s", uuid.New().String()),
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(PasswordResetExpiry),
+	}
+	limit := repository.SendLimit{
+		Cooldown: PasswordResetCooldown,
+		MaxSends: MaxPasswordResetSends,
+		Window:   PasswordResetWindow,
+	}
+	err = s.repo.CreatePasswordResetToken(ctx, resetToken, limit)
+	if errors.Is(err, model.ErrTooManyAttempts) {
+		s.logger.InfoContext(ctx, "password reset throttled", "user_id", user.ID)
+		return response, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, notification.Message{
+		To:      user.Email,
+		Subject: "Reset your FoodBuddy password",
+		Body: fmt.Sprintf("Use this token to reset your FoodBuddy password: %s\nIt expires in %d minutes. If you did not request a reset, you can ignore this email.",
+			token, int(PasswordResetExpiry.Minutes())),
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to send password reset email", "user_id", user.ID, "error", err)
+	}
+
+	return response, nil
+}
+
+// ConfirmPasswordReset sets a new password using a reset token and signs the
+// user out of every device
+func (s *UserService) ConfirmPasswordReset(ctx context.Context, req *userExtPb.ConfirmPasswordResetRequest) (*userExtPb.PasswordResetResponse, error) {
+	if req.ResetToken == "" {
+		return nil, model.ErrInvalidToken
+	}
+
+	passwordHash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.ResetPassword(ctx, auth.HashOpaqueToken(req.ResetToken), passwordHash); err != nil {
+		return nil, err
+	}
+
+	return &userExtPb.PasswordResetResponse{
+		Success: true,
+		Message: "Password has been reset. Please log in again.",
+	}, nil
+}
+
+// hashPassword enforces the password policy and returns the bcrypt hash
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", model.ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
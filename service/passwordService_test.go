@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+var resetTokenPattern = regexp.MustCompile(`password: (\S+)`)
+
+func TestConfirmPasswordResetIsSingleUse(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	_, oldToken := signUpVerified(t, s, mailer, "alice@example.com")
+
+	if _, err := s.RequestPasswordReset(ctx, &userExtPb.RequestPasswordResetRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	resetToken := mailer.lastMatch(t, "alice@example.com", resetTokenPattern)
+
+	_, err := s.ConfirmPasswordReset(ctx, &userExtPb.ConfirmPasswordResetRequest{ResetToken: resetToken, NewPassword: "short"})
+	if !errors.Is(err, model.ErrWeakPassword) {
+		t.Fatalf("ConfirmPasswordReset with a weak password = %v, want ErrWeakPassword", err)
+	}
+	if _, err := s.ConfirmPasswordReset(ctx, &userExtPb.ConfirmPasswordResetRequest{ResetToken: resetToken, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ConfirmPasswordReset: %v", err)
+	}
+	_, err = s.ConfirmPasswordReset(ctx, &userExtPb.ConfirmPasswordResetRequest{ResetToken: resetToken, NewPassword: "another-password"})
+	if !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("reused reset token = %v, want ErrInvalidToken", err)
+	}
+
+	if _, err := s.Authenticate(ctx, oldToken); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("access token issued before the reset = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "alice@example.com", Password: testPassword}); !errors.Is(err, model.ErrInvalidPassword) {
+		t.Errorf("login with the old password = %v, want ErrInvalidPassword", err)
+	}
+	if _, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: "alice@example.com", Password: "new-password"}); err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	s, _, mailer := newTestService(t)
+
+	resp, err := s.RequestPasswordReset(context.Background(), &userExtPb.RequestPasswordResetRequest{Email: "nobody@example.com"})
+	if err != nil || !resp.Success {
+		t.Errorf("RequestPasswordReset for an unknown email = %v, %v", resp, err)
+	}
+	if len(mailer.sent("nobody@example.com")) != 0 {
+		t.Error("reset email sent to an unknown address")
+	}
+}
+
+func TestRequestPasswordResetThrottled(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	signUpVerified(t, s, mailer, "alice@example.com")
+	before := len(mailer.sent("alice@example.com"))
+
+	request := &userExtPb.RequestPasswordResetRequest{Email: "alice@example.com"}
+	if _, err := s.RequestPasswordReset(ctx, request); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	resetToken := mailer.lastMatch(t, "alice@example.com", resetTokenPattern)
+
+	// A flood of requests gets the usual answer but sends no email and leaves the token valid
+	for i := 0; i < 3; i++ {
+		resp, err := s.RequestPasswordReset(ctx, request)
+		if err != nil || !resp.Success {
+			t.Fatalf("throttled RequestPasswordReset = %v, %v", resp, err)
+		}
+	}
+	if n := len(mailer.sent("alice@example.com")) - before; n != 1 {
+		t.Errorf("%d reset emails sent, want 1", n)
+	}
+	if _, err := s.ConfirmPasswordReset(ctx, &userExtPb.ConfirmPasswordResetRequest{ResetToken: resetToken, NewPassword: "new-password"}); err != nil {
+		t.Errorf("ConfirmPasswordReset after throttled requests: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+const (
+	// MaxReputationDelta bounds a single adjustment so one bad call cannot
+	// overflow or wipe out a score
+	MaxReputationDelta = 1000
+
+	DefaultLeaderboardSize = 10
+	MaxLeaderboardSize     = 100
+)
+
+// AdjustReputation records a reputation change reported by another FoodBuddy
+// service, such as after an order or a review. Retrying with the same
+// idempotency key returns the original result without applying it twice.
+func (s *UserService) AdjustReputation(ctx context.Context, req *userExtPb.AdjustReputationRequest) (*userExtPb.AdjustReputationResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.UserId == "":
+		return nil, fmt.Errorf("%w: userId is required", model.ErrInvalidArgument)
+	case req.Delta == 0:
+		return nil, fmt.Errorf("%w: delta must not be zero", model.ErrInvalidArgument)
+	case req.Delta > MaxReputationDelta || req.Delta < -MaxReputationDelta:
+		return nil, fmt.Errorf("%w: delta must be within ±%d", model.ErrInvalidArgument, MaxReputationDelta)
+	case req.SourceService == "":
+		return nil, fmt.Errorf("%w: sourceService is required", model.ErrInvalidArgument)
+	case req.IdempotencyKey == "":
+		return nil, fmt.Errorf("%w: idempotencyKey is required", model.ErrInvalidArgument)
+	case len(req.SourceService) > 64 || len(req.IdempotencyKey) > 255 || len(req.Reason) > 255 || len(req.ReferenceId) > 255:
+		return nil, fmt.Errorf("%w: sourceService is limited to 64 bytes and other fields to 255", model.ErrInvalidArgument)
+	}
+
+	event := &model.ReputationEvent{
+		ID:             fmt.Sprintf("rep_%s", uuid.New().String()),
+		UserID:         req.UserId,
+		Delta:          req.Delta,
+		Reason:         req.Reason,
+		SourceService:  req.SourceService,
+		ReferenceID:    req.ReferenceId,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	result, err := s.repo.AdjustReputation(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Replayed {
+		s.logger.InfoContext(ctx, "reputation adjusted",
+			"user_id", req.UserId, "delta", req.Delta, "source_service", req.SourceService,
+			"reference_id", req.ReferenceId, "reputation", result.Reputation)
+	}
+
+	return &userExtPb.AdjustReputationResponse{
+		Success:    true,
+		UserId:     req.UserId,
+		EventId:    result.Event.ID,
+		Reputation: result.Reputation,
+		Replayed:   result.Replayed,
+	}, nil
+}
+
+// GetReputationSummary returns a user's reputation with their tier, the
+// points needed for the next tier and their leaderboard rank
+func (s *UserService) GetReputationSummary(ctx context.Context, req *userExtPb.GetReputationSummaryRequest) (*userExtPb.ReputationSummaryResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	rank, err := s.repo.GetReputationRank(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &userExtPb.ReputationSummaryResponse{
+		Success:    true,
+		UserId:     user.ID,
+		Reputation: user.Reputation,
+		Rank:       rank,
+	}
+	current, next, ok := s.tiers.For(user.Reputation)
+	if ok {
+		resp.Tier = current.Name
+	}
+	if next != nil {
+		resp.NextTier = next.Name
+		resp.PointsToNextTier = next.MinReputation - user.Reputation
+	}
+	return resp, nil
+}
+
+// GetLeaderboard returns the unbanned users with the highest reputation and
+// the caller's own rank. Every signed-in user may see the leaderboard.
+func (s *UserService) GetLeaderboard(ctx context.Context, req *userExtPb.GetLeaderboardRequest) (*userExtPb.LeaderboardResponse, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: unauthenticated caller", model.ErrInvalidToken)
+	}
+
+	limit := int(req.Limit)
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("%w: limit must not be negative", model.ErrInvalidArgument)
+	case limit == 0:
+		limit = DefaultLeaderboardSize
+	case limit > MaxLeaderboardSize:
+		limit = MaxLeaderboardSize
+	}
+
+	entries, err := s.repo.GetLeaderboard(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	caller, err := s.repo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	callerRank, err := s.repo.GetReputationRank(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	pbEntries := make([]*userExtPb.LeaderboardEntry, 0, len(entries))
+	for _, entry := range entries {
+		pbEntries = append(pbEntries, s.toLeaderboardEntry(entry))
+	}
+	return &userExtPb.LeaderboardResponse{
+		Success: true,
+		Entries: pbEntries,
+		Caller:  s.toLeaderboardEntry(&repository.LeaderboardEntry{Rank: callerRank, User: caller}),
+	}, nil
+}
+
+func (s *UserService) toLeaderboardEntry(entry *repository.LeaderboardEntry) *userExtPb.LeaderboardEntry {
+	pbEntry := &userExtPb.LeaderboardEntry{
+		Rank:       entry.Rank,
+		UserId:     entry.User.ID,
+		Name:       entry.User.Name,
+		Reputation: entry.User.Reputation,
+	}
+	if tier, _, ok := s.tiers.For(entry.User.Reputation); ok {
+		pbEntry.Tier = tier.Name
+	}
+	return pbEntry
+}
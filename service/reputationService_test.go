@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/reputation"
+)
+
+var ordersPrincipal = &auth.Principal{UserID: "usr_orders", Roles: []model.Role{model.RoleService}}
+
+func TestAdjustReputationIsIdempotent(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 1)
+	ctx := callContext(ordersPrincipal, userExtPb.UserExtService_AdjustReputation_FullMethodName)
+	adjust := func(source, key string, delta int32) (*userExtPb.AdjustReputationResponse, error) {
+		return s.AdjustReputation(ctx, &userExtPb.AdjustReputationRequest{
+			UserId: "usr_000", Delta: delta, Reason: "order delivered", SourceService: source, IdempotencyKey: key,
+		})
+	}
+
+	first, err := adjust("orders", "order-1", 10)
+	if err != nil || first.Reputation != 10 || first.Replayed {
+		t.Fatalf("AdjustReputation = %v, %v", first, err)
+	}
+	retry, err := adjust("orders", "order-1", 10)
+	if err != nil || !retry.Replayed || retry.EventId != first.EventId || retry.Reputation != 10 {
+		t.Errorf("retried AdjustReputation = %v, %v, want the first result replayed", retry, err)
+	}
+	if _, err := adjust("orders", "order-1", 20); !errors.Is(err, model.ErrIdempotencyReused) {
+		t.Errorf("key reused with another delta = %v, want ErrIdempotencyReused", err)
+	}
+
+	// Keys are scoped to the calling service
+	if resp, err := adjust("reviews", "order-1", 5); err != nil || resp.Replayed || resp.Reputation != 15 {
+		t.Errorf("same key from another service = %v, %v", resp, err)
+	}
+	if resp, err := adjust("orders", "order-2", -3); err != nil || resp.Reputation != 12 {
+		t.Errorf("AdjustReputation with a new key = %v, %v", resp, err)
+	}
+	if user, _ := repo.GetUserByID(ctx, "usr_000"); user.Reputation != 12 {
+		t.Errorf("stored reputation = %d, want 12", user.Reputation)
+	}
+
+	for name, req := range map[string]*userExtPb.AdjustReputationRequest{
+		"zero delta":     {UserId: "usr_000", SourceService: "orders", IdempotencyKey: "k"},
+		"delta too big":  {UserId: "usr_000", Delta: MaxReputationDelta + 1, SourceService: "orders", IdempotencyKey: "k"},
+		"no source":      {UserId: "usr_000", Delta: 1, IdempotencyKey: "k"},
+		"no idempotency": {UserId: "usr_000", Delta: 1, SourceService: "orders"},
+		"no user":        {Delta: 1, SourceService: "orders", IdempotencyKey: "k"},
+	} {
+		if _, err := s.AdjustReputation(ctx, req); !errors.Is(err, model.ErrInvalidArgument) {
+			t.Errorf("%s: err = %v, want ErrInvalidArgument", name, err)
+		}
+	}
+
+	userCtx := ownerContext("usr_000", userExtPb.UserExtService_AdjustReputation_FullMethodName)
+	_, err = s.AdjustReputation(userCtx, &userExtPb.AdjustReputationRequest{UserId: "usr_000", Delta: 1000, SourceService: "orders", IdempotencyKey: "k"})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("AdjustReputation by a user = %v, want ErrPermissionDenied", err)
+	}
+}
+
+// setReputation gives a user reputation points through the ledger
+func setReputation(t *testing.T, repo repository.UserRepository, userID string, points int32) {
+	t.Helper()
+	_, err := repo.AdjustReputation(context.Background(), &model.ReputationEvent{
+		ID: "rep_" + userID, UserID: userID, Delta: points, SourceService: "test", IdempotencyKey: userID,
+	})
+	if err != nil {
+		t.Fatalf("AdjustReputation %s: %v", userID, err)
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	tiers, err := reputation.ParseTiers(reputation.DefaultTiers)
+	if err != nil {
+		t.Fatalf("ParseTiers: %v", err)
+	}
+	s.tiers = tiers
+	seedUsers(t, repo, 4)
+	for userID, points := range map[string]int32{"usr_000": 600, "usr_001": 150, "usr_002": 20, "usr_003": 900} {
+		setReputation(t, repo, userID, points)
+	}
+	if err := repo.BanUser(context.Background(), &model.UserBan{ID: "ban_1", UserID: "usr_003", BannedBy: "usr_admin"}); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	ctx := ownerContext("usr_002", userExtPb.UserExtService_GetLeaderboard_FullMethodName)
+
+	// Banned users are left out of the ranking
+	resp, err := s.GetLeaderboard(ctx, &userExtPb.GetLeaderboardRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	var entries []string
+	for _, e := range resp.Entries {
+		entries = append(entries, fmt.Sprintf("%d:%s:%s", e.Rank, e.UserId, e.Tier))
+	}
+	if want := []string{"1:usr_000:Gold", "2:usr_001:Silver"}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("leaderboard = %v, want %v", entries, want)
+	}
+	if c := resp.Caller; c.UserId != "usr_002" || c.Rank != 3 || c.Reputation != 20 || c.Tier != "Bronze" {
+		t.Errorf("caller entry = %v", c)
+	}
+
+	if resp, err := s.GetLeaderboard(ctx, &userExtPb.GetLeaderboardRequest{}); err != nil || len(resp.Entries) != 3 {
+		t.Errorf("GetLeaderboard with the default limit = %v, %v", resp, err)
+	}
+	if _, err := s.GetLeaderboard(ctx, &userExtPb.GetLeaderboardRequest{Limit: -1}); !errors.Is(err, model.ErrInvalidArgument) {
+		t.Errorf("negative limit = %v, want ErrInvalidArgument", err)
+	}
+	if _, err := s.GetLeaderboard(context.Background(), &userExtPb.GetLeaderboardRequest{}); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("unauthenticated GetLeaderboard = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestGetReputationSummary(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	tiers, err := reputation.ParseTiers(reputation.DefaultTiers)
+	if err != nil {
+		t.Fatalf("ParseTiers: %v", err)
+	}
+	s.tiers = tiers
+	seedUsers(t, repo, 2)
+	setReputation(t, repo, "usr_000", 150)
+	setReputation(t, repo, "usr_001", 600)
+
+	method := userExtPb.UserExtService_GetReputationSummary_FullMethodName
+	resp, err := s.GetReputationSummary(ownerContext("usr_000", method), &userExtPb.GetReputationSummaryRequest{UserId: "usr_000"})
+	if err != nil {
+		t.Fatalf("GetReputationSummary: %v", err)
+	}
+	if resp.Tier != "Silver" || resp.NextTier != "Gold" || resp.PointsToNextTier != 350 || resp.Rank != 2 {
+		t.Errorf("summary = %v", resp)
+	}
+	// The top tier has no next tier
+	resp, err = s.GetReputationSummary(ownerContext("usr_001", method), &userExtPb.GetReputationSummaryRequest{UserId: "usr_001"})
+	if err != nil || resp.Tier != "Gold" || resp.NextTier != "" || resp.Rank != 1 {
+		t.Errorf("summary at the top tier = %v, %v", resp, err)
+	}
+	_, err = s.GetReputationSummary(ownerContext("usr_000", method), &userExtPb.GetReputationSummaryRequest{UserId: "usr_001"})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("GetReputationSummary of another user = %v, want ErrPermissionDenied", err)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+var rolesFromProto = map[userExtPb.UserRole]model.Role{
+	userExtPb.UserRole_USER_ROLE_USER:      model.RoleUser,
+	userExtPb.UserRole_USER_ROLE_MODERATOR: model.RoleModerator,
+	userExtPb.UserRole_USER_ROLE_SUPPORT:   model.RoleSupport,
+	userExtPb.UserRole_USER_ROLE_ADMIN:     model.RoleAdmin,
+	userExtPb.UserRole_USER_ROLE_SERVICE:   model.RoleService,
+}
+
+var rolesToProto = map[model.Role]userExtPb.UserRole{
+	model.RoleUser:      userExtPb.UserRole_USER_ROLE_USER,
+	model.RoleModerator: userExtPb.UserRole_USER_ROLE_MODERATOR,
+	model.RoleSupport:   userExtPb.UserRole_USER_ROLE_SUPPORT,
+	model.RoleAdmin:     userExtPb.UserRole_USER_ROLE_ADMIN,
+	model.RoleService:   userExtPb.UserRole_USER_ROLE_SERVICE,
+}
+
+// GrantRole gives a user a moderator, support, admin or service role
+func (s *UserService) GrantRole(ctx context.Context, req *userExtPb.GrantRoleRequest) (*userExtPb.UserRolesResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	role, err := grantableRole(req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	grant := &model.UserRole{UserID: req.UserId, Role: role, GrantedBy: principal.UserID}
+	if err := s.repo.GrantRole(ctx, grant); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "role granted", "user_id", req.UserId, "role", role, "granted_by", principal.UserID)
+
+	return s.userRolesResponse(ctx, req.UserId)
+}
+
+// RevokeRole removes a moderator, support, admin or service role from a user
+func (s *UserService) RevokeRole(ctx context.Context, req *userExtPb.RevokeRoleRequest) (*userExtPb.UserRolesResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	role, err := grantableRole(req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keeps the service from being left without anyone able to grant roles
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if role == model.RoleAdmin && principal.UserID == req.UserId {
+		return nil, fmt.Errorf("%w: admins cannot revoke their own admin role", model.ErrPermissionDenied)
+	}
+
+	if err := s.repo.RevokeRole(ctx, req.UserId, role); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "role revoked", "user_id", req.UserId, "role", role, "revoked_by", principal.UserID)
+
+	return s.userRolesResponse(ctx, req.UserId)
+}
+
+// GetUserRoles lists the roles of a user, including the implicit user role
+func (s *UserService) GetUserRoles(ctx context.Context, req *userExtPb.GetUserRolesRequest) (*userExtPb.UserRolesResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetUserByID(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	return s.userRolesResponse(ctx, req.UserId)
+}
+
+func (s *UserService) userRolesResponse(ctx context.Context, userID string) (*userExtPb.UserRolesResponse, error) {
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pbRoles := []userExtPb.UserRole{userExtPb.UserRole_USER_ROLE_USER}
+	for _, role := range roles {
+		if pbRole, ok := rolesToProto[role]; ok && role != model.RoleUser {
+			pbRoles = append(pbRoles, pbRole)
+		}
+	}
+	return &userExtPb.UserRolesResponse{Success: true, UserId: userID, Roles: pbRoles}, nil
+}
+
+// grantableRole converts a requested role, rejecting the implicit user role
+func grantableRole(pbRole userExtPb.UserRole) (model.Role, error) {
+	role, ok := rolesFromProto[pbRole]
+	if !ok || role == model.RoleUser {
+		return "", fmt.Errorf("%w: %s cannot be granted or revoked", model.ErrInvalidRole, pbRole)
+	}
+	return role, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+// CreateSession logs a user in on a device and returns an access token together
+// with a long-lived refresh token.
+func (s *UserService) CreateSession(ctx context.Context, req *userExtPb.CreateSessionRequest) (*userExtPb.SessionResponse, error) {
+	user, err := s.authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &model.Session{
+		ID:               fmt.Sprintf("ses_%s", uuid.New().String()),
+		UserID:           user.ID,
+		RefreshTokenHash: tokenHash,
+		DeviceInfo:       req.DeviceInfo,
+		ExpiresAt:        time.Now().Add(RefreshTokenExpiry),
+	}
+	// The first session of a login starts its own family
+	session.FamilyID = session.ID
+
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.sessionResponse(user, session, refreshToken)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair.
+// Presenting a refresh token that was already rotated revokes the whole session
+// family, since it means the token has been used by someone else.
+func (s *UserService) RefreshToken(ctx context.Context, req *userExtPb.RefreshTokenRequest) (*userExtPb.SessionResponse, error) {
+	current, err := s.repo.GetSessionByTokenHash(ctx, auth.HashOpaqueToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			return nil, model.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		return nil, model.ErrInvalidToken
+	}
+
+	if current.RotatedAt != nil {
+		s.logger.WarnContext(ctx, "refresh token reused, revoking session family", "user_id", current.UserID, "family_id", current.FamilyID)
+		if err := s.repo.RevokeSessionFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, model.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, model.ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		return nil, model.ErrInvalidToken
+	}
+	if err := s.checkNotBanned(ctx, user); err != nil {
+		return nil, err
+	}
+
+	refreshToken, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	deviceInfo := req.DeviceInfo
+	if deviceInfo == "" {
+		deviceInfo = current.DeviceInfo
+	}
+
+	next := &model.Session{
+		ID:               fmt.Sprintf("ses_%s", uuid.New().String()),
+		UserID:           current.UserID,
+		FamilyID:         current.FamilyID,
+		RefreshTokenHash: tokenHash,
+		DeviceInfo:       deviceInfo,
+		ExpiresAt:        time.Now().Add(RefreshTokenExpiry),
+	}
+
+	if err := s.repo.RotateSession(ctx, current.ID, next); err != nil {
+		// Lost a race against another refresh with the same token
+		if errors.Is(err, model.ErrRefreshTokenReused) {
+			if revokeErr := s.repo.RevokeSessionFamily(ctx, current.FamilyID); revokeErr != nil {
+				return nil, revokeErr
+			}
+		}
+		return nil, err
+	}
+
+	return s.sessionResponse(user, next, refreshToken)
+}
+
+// Logout ends the session on the device owning the refresh token
+func (s *UserService) Logout(ctx context.Context, req *userExtPb.LogoutRequest) (*userExtPb.LogoutResponse, error) {
+	session, err := s.repo.GetSessionByTokenHash(ctx, auth.HashOpaqueToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			return nil, model.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if err := s.repo.RevokeSessionFamily(ctx, session.FamilyID); err != nil {
+		return nil, err
+	}
+
+	return &userExtPb.LogoutResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	}, nil
+}
+
+// LogoutAllDevices ends every session of a user and revokes their access
+// tokens, including those issued by UserLogin
+func (s *UserService) LogoutAllDevices(ctx context.Context, req *userExtPb.LogoutAllDevicesRequest) (*userExtPb.LogoutResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetUserByID(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RevokeUserSessions(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	return &userExtPb.LogoutResponse{
+		Success: true,
+		Message: "Logged out from all devices",
+	}, nil
+}
+
+func (s *UserService) sessionResponse(user *model.User, session *model.Session, refreshToken string) (*userExtPb.SessionResponse, error) {
+	accessToken, accessExpiresAt, err := s.tokens.Generate(user, session.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userExtPb.SessionResponse{
+		Success:               true,
+		UserId:                user.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt.Unix(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt.Unix(),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	signUpVerified(t, s, mailer, "alice@example.com")
+
+	first, err := s.CreateSession(ctx, &userExtPb.CreateSessionRequest{Email: "alice@example.com", Password: testPassword, DeviceInfo: "phone"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	second, err := s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatalf("refresh did not rotate the tokens: %+v", second)
+	}
+	if _, err := s.Authenticate(ctx, second.AccessToken); err != nil {
+		t.Errorf("rotated access token rejected: %v", err)
+	}
+
+	// Replaying the rotated token means it leaked, so the whole family ends
+	_, err = s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if !errors.Is(err, model.ErrRefreshTokenReused) {
+		t.Fatalf("reused refresh token = %v, want ErrRefreshTokenReused", err)
+	}
+	_, err = s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+	if !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("refresh token of a revoked family = %v, want ErrInvalidToken", err)
+	}
+	for name, token := range map[string]string{"first": first.AccessToken, "second": second.AccessToken} {
+		if _, err := s.Authenticate(ctx, token); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("%s access token of a revoked family = %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	// Other logins are unaffected
+	other, err := s.CreateSession(ctx, &userExtPb.CreateSessionRequest{Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Errorf("RefreshToken of another session: %v", err)
+	}
+}
+
+func TestLogoutAllDevices(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	userID, loginToken := signUpVerified(t, s, mailer, "alice@example.com")
+	_, otherToken := signUpVerified(t, s, mailer, "bob@example.com")
+	session, err := s.CreateSession(ctx, &userExtPb.CreateSessionRequest{Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	method := userExtPb.UserExtService_LogoutAllDevices_FullMethodName
+	_, err = s.LogoutAllDevices(tokenContext(t, s, otherToken, method), &userExtPb.LogoutAllDevicesRequest{UserId: userID})
+	if !errors.Is(err, model.ErrPermissionDenied) {
+		t.Errorf("LogoutAllDevices of another user = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := s.LogoutAllDevices(tokenContext(t, s, loginToken, method), &userExtPb.LogoutAllDevicesRequest{UserId: userID}); err != nil {
+		t.Fatalf("LogoutAllDevices: %v", err)
+	}
+
+	// Tokens from UserLogin belong to no session but are revoked all the same
+	for name, token := range map[string]string{"login": loginToken, "session": session.AccessToken} {
+		if _, err := s.Authenticate(ctx, token); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("%s access token after LogoutAllDevices = %v, want ErrInvalidToken", name, err)
+		}
+	}
+	if _, err := s.RefreshToken(ctx, &userExtPb.RefreshTokenRequest{RefreshToken: session.RefreshToken}); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("refresh token after LogoutAllDevices = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.Authenticate(ctx, otherToken); err != nil {
+		t.Errorf("token of another user rejected: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+// ValidateToken checks an access token for other FoodBuddy services and reports
+// the current verification and ban status of its user.
+func (s *UserService) ValidateToken(ctx context.Context, req *userExtPb.ValidateTokenRequest) (*userExtPb.ValidateTokenResponse, error) {
+	claims, user, err := s.validateToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userExtPb.ValidateTokenResponse{
+		Valid:      true,
+		UserId:     user.ID,
+		IsVerified: user.IsVerified,
+		IsBanned:   user.IsBanned,
+		IssuedAt:   claims.IssuedAt.Unix(),
+		ExpiresAt:  claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+// Authenticate resolves the bearer token of an RPC to its caller. Roles and
+// bans are loaded on every call so they apply to tokens already issued.
+func (s *UserService) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	claims, user, err := s.validateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotBanned(ctx, user); err != nil {
+		return nil, err
+	}
+	roles, err := s.repo.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{UserID: user.ID, SessionID: claims.SessionID, Roles: roles}, nil
+}
+
+// validateToken parses an access token and checks that its session and user
+// still exist and that the password has not changed since it was issued
+func (s *UserService) validateToken(ctx context.Context, token string) (*auth.Claims, *model.User, error) {
+	claims, err := s.tokens.Parse(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Tokens issued for a session die with it on logout or refresh token reuse
+	if claims.SessionID != "" {
+		active, err := s.repo.IsSessionFamilyActive(ctx, claims.SessionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !active {
+			return nil, nil, model.ErrInvalidToken
+		}
+	}
+
+	// A token for a deleted user is no longer valid
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, model.ErrInvalidToken
+	}
+
+	// Changing or resetting the password revokes tokens issued before,
+	// including those of UserLogin which have no session
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, nil, model.ErrInvalidToken
+	}
+	return claims, user, nil
+}
+
+// GetUserByToken resolves an access token to the profile of its user. Tokens
+// of ended sessions are rejected as they are by ValidateToken.
+func (s *UserService) GetUserByToken(ctx context.Context, req *userPb.GetUserByTokenRequest) (*userPb.GetProfileResponse, error) {
+	_, user, err := s.validateToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userPb.GetProfileResponse{
+		UserId:      user.ID,
+		Email:       user.Email,
+		Name:        user.Name,
+		Reputation:  user.Reputation,
+		PhoneNumber: user.PhoneNumber,
+		IsVerified:  user.IsVerified,
+		IsBanned:    user.IsBanned,
+	}, nil
+}
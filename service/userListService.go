@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+var userSortFields = map[userExtPb.UserSortField]repository.UserSortField{
+	userExtPb.UserSortField_USER_SORT_FIELD_CREATED_AT: repository.SortByCreatedAt,
+	userExtPb.UserSortField_USER_SORT_FIELD_NAME:       repository.SortByName,
+	userExtPb.UserSortField_USER_SORT_FIELD_EMAIL:      repository.SortByEmail,
+	userExtPb.UserSortField_USER_SORT_FIELD_REPUTATION: repository.SortByReputation,
+}
+
+// ListUsers returns a filtered, sorted page of users for the admin dashboard
+func (s *UserService) ListUsers(ctx context.Context, req *userExtPb.ListUsersRequest) (*userExtPb.ListUsersResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize <= 0:
+		pageSize = DefaultPageSize
+	case pageSize > MaxPageSize:
+		pageSize = MaxPageSize
+	}
+
+	sortBy, ok := userSortFields[req.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported sort field %v", model.ErrInvalidArgument, req.SortBy)
+	}
+
+	afterID, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := req.GetFilter()
+	if filter == nil {
+		filter = &userExtPb.UserFilter{}
+	}
+	page, err := s.repo.ListUsers(ctx, repository.UserListOptions{
+		Filter: repository.UserFilter{
+			IsBanned:      filter.IsBanned,
+			IsVerified:    filter.IsVerified,
+			MinReputation: filter.MinReputation,
+			MaxReputation: filter.MaxReputation,
+			Email:         filter.GetEmailContains(),
+			Name:          filter.GetNameContains(),
+		},
+		SortBy:     sortBy,
+		Descending: req.Descending,
+		Limit:      pageSize,
+		AfterID:    afterID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := make([]*userPb.GetProfileResponse, 0, len(page.Users))
+	for _, user := range page.Users {
+		users = append(users, toProfileResponse(user))
+	}
+
+	return &userExtPb.ListUsersResponse{
+		Success:       true,
+		Users:         users,
+		NextPageToken: encodePageToken(page.NextAfterID),
+		TotalCount:    page.TotalCount,
+	}, nil
+}
+
+// ExportUsers streams every user in ID order, reading the table in batches so
+// the full result set is never held in memory
+func (s *UserService) ExportUsers(req *userExtPb.ExportUsersRequest, stream userExtPb.UserExtService_ExportUsersServer) error {
+	ctx := stream.Context()
+	if err := authorize(ctx); err != nil {
+		return err
+	}
+
+	batchSize := int(req.BatchSize)
+	switch {
+	case batchSize <= 0:
+		batchSize = DefaultExportBatchSize
+	case batchSize > MaxExportBatchSize:
+		batchSize = MaxExportBatchSize
+	}
+
+	afterID := ""
+	for {
+		// Stop as soon as the client goes away instead of reading the rest of the table
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		users, err := s.repo.ScanUsers(ctx, afterID, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to export users: %w", err)
+		}
+
+		for _, user := range users {
+			if err := stream.Send(toProfileResponse(user)); err != nil {
+				return err
+			}
+		}
+
+		if len(users) < batchSize {
+			return nil
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+// Page tokens are opaque to clients; they carry the ID of the last user of the previous page
+func encodePageToken(afterID string) string {
+	if afterID == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(afterID))
+}
+
+func decodePageToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	afterID, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid page token", model.ErrInvalidArgument)
+	}
+	return string(afterID), nil
+}
+
+func toProfileResponse(user *model.User) *userPb.GetProfileResponse {
+	return &userPb.GetProfileResponse{
+		UserId:      user.ID,
+		Email:       user.Email,
+		Name:        user.Name,
+		Reputation:  user.Reputation,
+		PhoneNumber: user.PhoneNumber,
+		IsVerified:  user.IsVerified,
+		IsBanned:    user.IsBanned,
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"google.golang.org/grpc"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+var supportPrincipal = &auth.Principal{UserID: "usr_support", Roles: []model.Role{model.RoleSupport}}
+
+// seedUsers stores n users directly, skipping signup, one second apart
+func seedUsers(t *testing.T, repo repository.UserRepository, n int) {
+	t.Helper()
+	start := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := 0; i < n; i++ {
+		user := &model.User{
+			ID:        fmt.Sprintf("usr_%03d", i),
+			Email:     fmt.Sprintf("user%03d@example.com", i),
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+		}
+		if err := repo.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser %s: %v", user.ID, err)
+		}
+	}
+}
+
+func TestGetAllUsersReturnsEveryPage(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, MaxPageSize+5)
+
+	ctx := callContext(supportPrincipal, userPb.UserService_GetAllUsers_FullMethodName)
+	resp, err := s.GetAllUsers(ctx, &userPb.GetAllUsersRequest{})
+	if err != nil {
+		t.Fatalf("GetAllUsers: %v", err)
+	}
+	if len(resp.Users) != MaxPageSize+5 {
+		t.Fatalf("GetAllUsers returned %d users, want %d", len(resp.Users), MaxPageSize+5)
+	}
+	for i, user := range resp.Users {
+		if want := fmt.Sprintf("usr_%03d", i); user.UserId != want {
+			t.Fatalf("user %d = %s, want %s in signup order", i, user.UserId, want)
+		}
+	}
+}
+
+func TestListUsersPageSize(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, MaxPageSize+5)
+	ctx := callContext(supportPrincipal, userExtPb.UserExtService_ListUsers_FullMethodName)
+
+	tests := []struct {
+		pageSize int32
+		want     int
+	}{
+		{0, DefaultPageSize},
+		{-1, DefaultPageSize},
+		{7, 7},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+	for _, tt := range tests {
+		resp, err := s.ListUsers(ctx, &userExtPb.ListUsersRequest{PageSize: tt.pageSize})
+		if err != nil {
+			t.Fatalf("ListUsers with page size %d: %v", tt.pageSize, err)
+		}
+		if len(resp.Users) != tt.want || resp.TotalCount != MaxPageSize+5 {
+			t.Errorf("page size %d listed %d of %d users, want %d", tt.pageSize, len(resp.Users), resp.TotalCount, tt.want)
+		}
+	}
+}
+
+func TestListUsersPageToken(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 5)
+	ctx := callContext(supportPrincipal, userExtPb.UserExtService_ListUsers_FullMethodName)
+
+	var ids []string
+	request := &userExtPb.ListUsersRequest{PageSize: 2}
+	for pages := 0; pages < 5; pages++ {
+		resp, err := s.ListUsers(ctx, request)
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		for _, user := range resp.Users {
+			ids = append(ids, user.UserId)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		if resp.NextPageToken == resp.Users[len(resp.Users)-1].UserId {
+			t.Errorf("page token %q exposes the user ID", resp.NextPageToken)
+		}
+		request.PageToken = resp.NextPageToken
+	}
+	want := []string{"usr_000", "usr_001", "usr_002", "usr_003", "usr_004"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("paged users = %v, want %v", ids, want)
+	}
+
+	for _, req := range []*userExtPb.ListUsersRequest{
+		{PageToken: "not a token!"},
+		{SortBy: userExtPb.UserSortField(99)},
+	} {
+		if _, err := s.ListUsers(ctx, req); !errors.Is(err, model.ErrInvalidArgument) {
+			t.Errorf("ListUsers(%v) = %v, want ErrInvalidArgument", req, err)
+		}
+	}
+}
+
+// exportStream collects the users sent by ExportUsers. Cancel, when set, is
+// called after the first user is sent, as if the client went away.
+type exportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	sent   []string
+}
+
+func (s *exportStream) Context() context.Context { return s.ctx }
+
+func (s *exportStream) Send(user *userPb.GetProfileResponse) error {
+	s.sent = append(s.sent, user.UserId)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
+
+// scanRecorder records the batch size of every ScanUsers call
+type scanRecorder struct {
+	repository.UserRepository
+	limits []int
+}
+
+func (r *scanRecorder) ScanUsers(ctx context.Context, afterID string, limit int) ([]*model.User, error) {
+	r.limits = append(r.limits, limit)
+	return r.UserRepository.ScanUsers(ctx, afterID, limit)
+}
+
+func TestExportUsersStreamsInBatches(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 7)
+	recorder := &scanRecorder{UserRepository: repo}
+	s.repo = recorder
+	ctx := callContext(supportPrincipal, userExtPb.UserExtService_ExportUsers_FullMethodName)
+
+	stream := &exportStream{ctx: ctx}
+	if err := s.ExportUsers(&userExtPb.ExportUsersRequest{BatchSize: 3}, stream); err != nil {
+		t.Fatalf("ExportUsers: %v", err)
+	}
+	want := []string{"usr_000", "usr_001", "usr_002", "usr_003", "usr_004", "usr_005", "usr_006"}
+	if !reflect.DeepEqual(stream.sent, want) {
+		t.Errorf("exported users = %v, want %v", stream.sent, want)
+	}
+	if !reflect.DeepEqual(recorder.limits, []int{3, 3, 3}) {
+		t.Errorf("ScanUsers batch sizes = %v, want [3 3 3]", recorder.limits)
+	}
+
+	for batchSize, want := range map[int32]int{0: DefaultExportBatchSize, MaxExportBatchSize + 1: MaxExportBatchSize} {
+		recorder.limits = nil
+		if err := s.ExportUsers(&userExtPb.ExportUsersRequest{BatchSize: batchSize}, &exportStream{ctx: ctx}); err != nil {
+			t.Fatalf("ExportUsers: %v", err)
+		}
+		if !reflect.DeepEqual(recorder.limits, []int{want}) {
+			t.Errorf("batch size %d scanned with %v, want [%d]", batchSize, recorder.limits, want)
+		}
+	}
+}
+
+func TestExportUsersStopsWhenCanceled(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	seedUsers(t, repo, 7)
+	recorder := &scanRecorder{UserRepository: repo}
+	s.repo = recorder
+	ctx, cancel := context.WithCancel(callContext(supportPrincipal, userExtPb.UserExtService_ExportUsers_FullMethodName))
+	defer cancel()
+
+	// The batch being sent is finished, but no further batch is read
+	stream := &exportStream{ctx: ctx, cancel: cancel}
+	err := s.ExportUsers(&userExtPb.ExportUsersRequest{BatchSize: 2}, stream)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportUsers after the client went away = %v, want context.Canceled", err)
+	}
+	if len(stream.sent) != 2 || len(recorder.limits) != 1 {
+		t.Errorf("sent %v in %d batches, want one batch of 2", stream.sent, len(recorder.limits))
+	}
+}
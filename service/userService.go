@@ -4,46 +4,93 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/google/uuid"
 	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/geocoder"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/metrics"
 	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
 	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/reputation"
 )
 
 const (
-	TokenExpiry = 24 * time.Hour
+	TokenExpiry        = 24 * time.Hour
+	RefreshTokenExpiry = 30 * 24 * time.Hour
+
+	VerificationCodeExpiry  = 15 * time.Minute
+	MaxVerificationAttempts = 5
+
+	// Each code brings MaxVerificationAttempts guesses, so resends are
+	// throttled to keep the total number of guesses bounded
+	VerificationResendCooldown = time.Minute
+	VerificationResendWindow   = 24 * time.Hour
+	MaxVerificationSends       = 5
+
+	PasswordResetExpiry = 30 * time.Minute
+	MinPasswordLength   = 8
+
+	// Reset emails are throttled like verification codes, as each one
+	// replaces the previous reset token
+	PasswordResetCooldown = time.Minute
+	PasswordResetWindow   = 24 * time.Hour
+	MaxPasswordResetSends = 5
+
+	EmailChangeCodeExpiry = 15 * time.Minute
+
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	DefaultExportBatchSize = 500
+	MaxExportBatchSize     = 1000
 )
 
 type UserService struct {
 	userPb.UnimplementedUserServiceServer
-	repo repository.UserRepository
+	userExtPb.UnimplementedUserExtServiceServer
+	repo     repository.UserRepository
+	tokens   *auth.TokenManager
+	mailer   notification.Mailer
+	geocoder geocoder.Geocoder
+	tiers    reputation.Tiers
+	// maxAddresses caps the addresses of each user, 0 meaning no cap
+	maxAddresses int
+	logger       *slog.Logger
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repository.UserRepository, tokens *auth.TokenManager, mailer notification.Mailer, geocoder geocoder.Geocoder, tiers reputation.Tiers, maxAddresses int, logger *slog.Logger) *UserService {
+	return &UserService{repo: repo, tokens: tokens, mailer: mailer, geocoder: geocoder, tiers: tiers, maxAddresses: maxAddresses, logger: logger}
 }
 
+// GetAllUsers returns every user ordered by signup time, reading them a page
+// at a time.
+// Deprecated: use ListUsers, which supports pagination and filtering.
 func (s *UserService) GetAllUsers(ctx context.Context, req *userPb.GetAllUsersRequest) (*userPb.GetAllUsersResponse, error) {
-	users, err := s.repo.GetAllUsers()
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve users: %w", err)
+	if err := authorize(ctx); err != nil {
+		return nil, err
 	}
 
 	var userResponses []*userPb.GetProfileResponse
-	for _, user := range users {
-		userResponses = append(userResponses, &userPb.GetProfileResponse{
-			UserId:      user.ID,
-			Email:       user.Email,
-			Name:        user.Name,
-			Reputation:  user.Reputation,
-			PhoneNumber: user.PhoneNumber,
-			IsVerified:  user.IsVerified,
-			IsBanned:    user.IsBanned,
-		})
+	options := repository.UserListOptions{Limit: MaxPageSize}
+	for {
+		page, err := s.repo.ListUsers(ctx, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve users: %w", err)
+		}
+		for _, user := range page.Users {
+			userResponses = append(userResponses, toProfileResponse(user))
+		}
+		if page.NextAfterID == "" {
+			break
+		}
+		options.AfterID = page.NextAfterID
 	}
 
 	return &userPb.GetAllUsersResponse{
@@ -53,7 +100,7 @@ func (s *UserService) GetAllUsers(ctx context.Context, req *userPb.GetAllUsersRe
 }
 
 func (s *UserService) UserSignup(ctx context.Context, req *userPb.UserSignupRequest) (*userPb.UserSignupResponse, error) {
-	existingUser, err := s.repo.GetUserByEmail(req.Email)
+	existingUser, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err == nil && existingUser != nil {
 		return nil, model.ErrDuplicateEmail
 	}
@@ -64,20 +111,37 @@ func (s *UserService) UserSignup(ctx context.Context, req *userPb.UserSignupRequ
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	code, codeHash, err := auth.NewVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	codeExpiresAt := now.Add(VerificationCodeExpiry)
+
 	user := model.User{
-		ID:           fmt.Sprintf("usr_%s", uuid.New().String()),
-		Email:        req.Email,
-		PasswordHash: string(passwordHash),
-		Name:         req.FirstName,
-		PhoneNumber:  req.PhoneNumber,
-		Reputation:   0,
-		IsVerified:   true,
+		ID:                    fmt.Sprintf("usr_%s", uuid.New().String()),
+		Email:                 req.Email,
+		PasswordHash:          string(passwordHash),
+		Name:                  req.FirstName,
+		PhoneNumber:           req.PhoneNumber,
+		Reputation:            0,
+		IsVerified:            false,
+		VerificationCode:      codeHash,
+		VerificationExpiresAt: &codeExpiresAt,
+		VerificationSentAt:    &now,
+		VerificationSends:     1,
 	}
 
-	if err := s.repo.CreateUser(&user); err != nil {
+	if err := s.repo.CreateUser(ctx, &user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// The account exists at this point, a lost email can be recovered with ResendVerification
+	metrics.Signups.Inc()
+	if err := s.sendVerificationCode(ctx, &user, code); err != nil {
+		s.logger.WarnContext(ctx, "failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
 	return &userPb.UserSignupResponse{
 		Success: true,
 		Message: "Registration successful. Please check your email for verification.",
@@ -87,33 +151,84 @@ func (s *UserService) UserSignup(ctx context.Context, req *userPb.UserSignupRequ
 
 // Login verifies credentials and returns a token
 func (s *UserService) UserLogin(ctx context.Context, req *userPb.UserLoginRequest) (*userPb.UserLoginResponse, error) {
-	user, err := s.repo.GetUserByEmail(req.Email)
+	user, err := s.authenticate(ctx, req.Email, req.Password)
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, model.ErrInvalidPassword
+	token, _, err := s.tokens.Generate(user, "")
+	if err != nil {
+		return nil, err
 	}
 
 	return &userPb.UserLoginResponse{
-		UserId: user.ID,
+		Success: true,
+		UserId:  user.ID,
+		Token:   token,
+		Message: "Login successful",
 	}, nil
 }
 
-// VerifyEmail handles email verification
+// authenticate looks up a user by email and verifies their password. Only
+// users who verified their email may log in, so an account registered with
+// someone else's address grants nothing. It backs every login RPC, so login
+// metrics are recorded here.
+func (s *UserService) authenticate(ctx context.Context, email, password string) (*model.User, error) {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			metrics.FailedLogins.Inc()
+		}
+		return nil, err
+	}
+
+	// Verify password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		metrics.FailedLogins.Inc()
+		return nil, model.ErrInvalidPassword
+	}
+	if !user.IsVerified {
+		return nil, model.ErrUserNotVerified
+	}
+	if err := s.checkNotBanned(ctx, user); err != nil {
+		return nil, err
+	}
+
+	metrics.Logins.Inc()
+	return user, nil
+}
+
+// VerifyEmail handles email verification. It issues no token; the user logs
+// in once verified.
 func (s *UserService) VerifyEmail(ctx context.Context, req *userPb.EmailVerificationRequest) (*userPb.EmailVerificationResponse, error) {
-	user, err := s.repo.GetUserByEmail(req.UserId)
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
-	if user.VerificationCode != req.VerificationCode {
+	if user.IsVerified {
+		return &userPb.EmailVerificationResponse{
+			Success: true,
+			Message: "Email already verified",
+		}, nil
+	}
+
+	if user.VerificationAttempts >= MaxVerificationAttempts {
+		return nil, model.ErrTooManyAttempts
+	}
+
+	if user.VerificationCode == "" || user.VerificationExpiresAt == nil || time.Now().After(*user.VerificationExpiresAt) {
+		return nil, model.ErrCodeExpired
+	}
+
+	if !auth.CodeMatches(req.VerificationCode, user.VerificationCode) {
+		if err := s.repo.IncrementVerificationAttempts(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to record verification attempt: %w", err)
+		}
 		return nil, model.ErrInvalidCode
 	}
 
-	if err := s.repo.UpdateUserVerification(user.ID, true); err != nil {
+	if err := s.repo.UpdateUserVerification(ctx, user.ID, true); err != nil {
 		return nil, fmt.Errorf("failed to update verification status: %w", err)
 	}
 
@@ -125,9 +240,13 @@ func (s *UserService) VerifyEmail(ctx context.Context, req *userPb.EmailVerifica
 
 // GetProfile retrieves user profile
 func (s *UserService) GetProfile(ctx context.Context, req *userPb.GetProfileRequest) (*userPb.GetProfileResponse, error) {
-	user, err := s.repo.GetUserProfile(req.UserId)
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserProfile(ctx, req.UserId)
 	if err != nil {
-		return nil, model.ErrUserNotFound
+		return nil, err
 	}
 
 	return &userPb.GetProfileResponse{
@@ -142,15 +261,15 @@ func (s *UserService) GetProfile(ctx context.Context, req *userPb.GetProfileRequ
 
 // UpdateProfile updates user profile information
 func (s *UserService) UpdateProfile(ctx context.Context, req *userPb.UpdateProfileRequest) (*userPb.UpdateProfileResponse, error) {
-	// Verify token first
-	fmt.Println("the request is ", req)
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
 
 	// Fetch user by ID
-	user, err := s.repo.GetUserByID(req.UserId)
+	user, err := s.repo.GetUserByID(ctx, req.UserId)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("the profile is ", req)
 
 	// Update user fields if new values are provided
 	if req.Name != "" {
@@ -162,12 +281,12 @@ func (s *UserService) UpdateProfile(ctx context.Context, req *userPb.UpdateProfi
 	}
 
 	// Save updated user profile in repository
-	if err := s.repo.UpdateUser(user); err != nil {
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
 	// Refetch the updated user data to ensure data consistency
-	user, err = s.repo.GetUserByID(req.UserId)
+	user, err = s.repo.GetUserByID(ctx, req.UserId)
 	if err != nil {
 		return nil, err
 	}
@@ -189,8 +308,11 @@ func (s *UserService) UpdateProfile(ctx context.Context, req *userPb.UpdateProfi
 }
 
 func (s *UserService) CheckBan(ctx context.Context, req *userPb.CheckBanRequest) (*userPb.CheckBanResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
 
-	status, error := s.repo.CheckBan(req.UserId)
+	status, error := s.repo.CheckBan(ctx, req.UserId)
 
 	return &userPb.CheckBanResponse{
 		BanStatus: status,
@@ -199,18 +321,25 @@ func (s *UserService) CheckBan(ctx context.Context, req *userPb.CheckBanRequest)
 }
 
 func (s *UserService) BanUser(ctx context.Context, req *userPb.BanUserRequest) (*userPb.BanUserResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return &userPb.BanUserResponse{
+			Success: false,
+			Message: "User Ban failed",
+		}, err
+	}
+
 	if req.UserId == "" {
 		return &userPb.BanUserResponse{
 			Success: false,
 			Message: "User Ban failed",
-		}, errors.New("userId doesnt exist")
+		}, fmt.Errorf("%w: userId is required", model.ErrInvalidArgument)
 	}
 
-	if err := s.repo.BanUser(req.UserId); err != nil {
+	if _, err := s.banUser(ctx, req.UserId, "", 0); err != nil {
 		return &userPb.BanUserResponse{
 			Success: false,
 			Message: "User Ban failed",
-		}, errors.New(err.Error())
+		}, err
 	}
 
 	return &userPb.BanUserResponse{
@@ -221,19 +350,28 @@ func (s *UserService) BanUser(ctx context.Context, req *userPb.BanUserRequest) (
 }
 
 func (s *UserService) UnBanUser(ctx context.Context, req *userPb.UnBanUserRequest) (*userPb.UnBanUserResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return &userPb.UnBanUserResponse{
+			Success: false,
+			Message: "User UnBan failed",
+		}, err
+	}
+
 	if req.UserId == "" {
 		return &userPb.UnBanUserResponse{
 			Success: false,
 			Message: "User UnBan failed",
-		}, errors.New("userId doesnt exist")
+		}, fmt.Errorf("%w: userId is required", model.ErrInvalidArgument)
 	}
 
-	if err := s.repo.UnBanUser(req.UserId); err != nil {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if err := s.repo.UnBanUser(ctx, req.UserId, principal.UserID); err != nil {
 		return &userPb.UnBanUserResponse{
 			Success: false,
 			Message: "User UnBan failed",
-		}, errors.New(err.Error())
+		}, err
 	}
+	s.logger.InfoContext(ctx, "user unbanned", "user_id", req.UserId, "lifted_by", principal.UserID)
 
 	return &userPb.UnBanUserResponse{
 		Success: true,
@@ -243,16 +381,20 @@ func (s *UserService) UnBanUser(ctx context.Context, req *userPb.UnBanUserReques
 
 // Add methods to the existing UserService struct
 func (s *UserService) AddAddress(ctx context.Context, req *userPb.AddAddressRequest) (*userPb.AddAddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	// Convert protobuf Address to repository UserAddress
-	address := &model.UserAddress{
-		StreetName: req.Address.StreetName,
-		Locality:   req.Address.Locality,
-		State:      req.Address.State,
-		Pincode:    req.Address.Pincode,
+	address, err := addressFromProto(req.Address)
+	if err != nil {
+		return nil, err
 	}
+	address.Label = model.AddressLabelOther
+	s.geocode(ctx, address)
 
 	// Add the address
-	addressID, err := s.repo.AddAddress(req.UserId, address)
+	addressID, err := s.repo.AddAddress(ctx, req.UserId, address, s.maxAddresses)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add address: %w", err)
 	}
@@ -264,9 +406,14 @@ func (s *UserService) AddAddress(ctx context.Context, req *userPb.AddAddressRequ
 	}, nil
 }
 
+// GetAddresses returns a user's addresses with the default first
 func (s *UserService) GetAddresses(ctx context.Context, req *userPb.GetAddressesRequest) (*userPb.GetAddressesResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	// Retrieve addresses
-	addresses, err := s.repo.GetAddresses(req.UserId)
+	addresses, err := s.repo.GetAddresses(ctx, req.UserId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve addresses: %w", err)
 	}
@@ -291,16 +438,19 @@ func (s *UserService) GetAddresses(ctx context.Context, req *userPb.GetAddresses
 }
 
 func (s *UserService) EditAddress(ctx context.Context, req *userPb.EditAddressRequest) (*userPb.EditAddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	// Convert protobuf Address to repository UserAddress
-	address := &model.UserAddress{
-		StreetName: req.Address.StreetName,
-		Locality:   req.Address.Locality,
-		State:      req.Address.State,
-		Pincode:    req.Address.Pincode,
+	address, err := addressFromProto(req.Address)
+	if err != nil {
+		return nil, err
 	}
+	s.geocode(ctx, address)
 
 	// Edit the address
-	if err := s.repo.EditAddress(req.UserId, req.AddressId, address); err != nil {
+	if err := s.repo.EditAddress(ctx, req.UserId, req.AddressId, address); err != nil {
 		return nil, fmt.Errorf("failed to edit address: %w", err)
 	}
 
@@ -311,8 +461,12 @@ func (s *UserService) EditAddress(ctx context.Context, req *userPb.EditAddressRe
 }
 
 func (s *UserService) DeleteAddress(ctx context.Context, req *userPb.DeleteAddressRequest) (*userPb.DeleteAddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	// Delete the address
-	if err := s.repo.DeleteAddress(req.UserId, req.AddressId); err != nil {
+	if err := s.repo.DeleteAddress(ctx, req.UserId, req.AddressId); err != nil {
 		return nil, fmt.Errorf("failed to delete address: %w", err)
 	}
 
@@ -323,8 +477,12 @@ func (s *UserService) DeleteAddress(ctx context.Context, req *userPb.DeleteAddre
 }
 
 func (s *UserService) ValidateUserAddress(ctx context.Context, req *userPb.ValidateUserAddressRequest) (*userPb.ValidateUserAddressResponse, error) {
+	if err := authorizeUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	// Get all addresses for the user
-	addresses, err := s.repo.GetAddresses(req.UserId)
+	addresses, err := s.repo.GetAddresses(ctx, req.UserId)
 	if err != nil {
 		return &userPb.ValidateUserAddressResponse{
 			IsValid: false,
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/geocoder"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+const testPassword = "correct-horse"
+
+// recordingMailer keeps every message so tests can read the codes and tokens
+// sent to users
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []notification.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg notification.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// sent returns the messages sent to the address
+func (m *recordingMailer) sent(to string) []notification.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var messages []notification.Message
+	for _, msg := range m.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// lastMatch returns the first group of pattern in the last message sent to the address
+func (m *recordingMailer) lastMatch(t *testing.T, to string, pattern *regexp.Regexp) string {
+	t.Helper()
+	messages := m.sent(to)
+	if len(messages) == 0 {
+		t.Fatalf("no message sent to %s", to)
+	}
+	match := pattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		t.Fatalf("last message to %s does not match %s: %q", to, pattern, messages[len(messages)-1].Body)
+	}
+	return match[1]
+}
+
+var codePattern = regexp.MustCompile(`code is (\d+)`)
+
+func (m *recordingMailer) lastCode(t *testing.T, to string) string {
+	t.Helper()
+	return m.lastMatch(t, to, codePattern)
+}
+
+func newTestService(t *testing.T) (*UserService, repository.UserRepository, *recordingMailer) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	tokens, err := auth.NewTokenManager("test-secret", TokenExpiry)
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	mailer := &recordingMailer{}
+	return NewUserService(repo, tokens, mailer, geocoder.Disabled{}, nil, 0, testLogger), repo, mailer
+}
+
+// signUp registers an unverified user and returns their ID
+func signUp(t *testing.T, s *UserService, email string) string {
+	t.Helper()
+	resp, err := s.UserSignup(context.Background(), &userPb.UserSignupRequest{Email: email, Password: testPassword, FirstName: "Test"})
+	if err != nil {
+		t.Fatalf("UserSignup(%s): %v", email, err)
+	}
+	return resp.UserId
+}
+
+// signUpVerified registers and verifies a user, logs them in and returns
+// their ID and access token
+func signUpVerified(t *testing.T, s *UserService, mailer *recordingMailer, email string) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	userID := signUp(t, s, email)
+	if _, err := s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: mailer.lastCode(t, email)}); err != nil {
+		t.Fatalf("VerifyEmail(%s): %v", email, err)
+	}
+	resp, err := s.UserLogin(ctx, &userPb.UserLoginRequest{Email: email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("UserLogin(%s): %v", email, err)
+	}
+	return userID, resp.Token
+}
+
+// methodStream reports the method of an RPC, as the gRPC server does for handlers
+type methodStream struct{ method string }
+
+func (s methodStream) Method() string                { return s.method }
+func (methodStream) SetHeader(md metadata.MD) error  { return nil }
+func (methodStream) SendHeader(md metadata.MD) error { return nil }
+func (methodStream) SetTrailer(md metadata.MD) error { return nil }
+
+// callContext returns the context of a call to method made by principal
+func callContext(principal *auth.Principal, method string) context.Context {
+	ctx := auth.WithPrincipal(context.Background(), principal)
+	return grpc.NewContextWithServerTransportStream(ctx, methodStream{method})
+}
+
+// tokenContext authenticates token as the auth interceptor does and returns
+// the context of a call to method made with it
+func tokenContext(t *testing.T, s *UserService, token, method string) context.Context {
+	t.Helper()
+	principal, err := s.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	return callContext(principal, method)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	ctx := context.Background()
+	userID := signUp(t, s, "alice@example.com")
+	login := &userPb.UserLoginRequest{Email: "alice@example.com", Password: testPassword}
+
+	if _, err := s.UserLogin(ctx, login); !errors.Is(err, model.ErrUserNotVerified) {
+		t.Errorf("UserLogin before verification = %v, want ErrUserNotVerified", err)
+	}
+	_, err := s.CreateSession(ctx, &userExtPb.CreateSessionRequest{Email: login.Email, Password: login.Password})
+	if !errors.Is(err, model.ErrUserNotVerified) {
+		t.Errorf("CreateSession before verification = %v, want ErrUserNotVerified", err)
+	}
+
+	_, err = s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: "not-the-code"})
+	if !errors.Is(err, model.ErrInvalidCode) {
+		t.Fatalf("VerifyEmail with a wrong code = %v, want ErrInvalidCode", err)
+	}
+
+	resp, err := s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: mailer.lastCode(t, "alice@example.com")})
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if resp.Token != "" {
+		t.Error("VerifyEmail issued an access token")
+	}
+	user, _ := repo.GetUserByID(ctx, userID)
+	if !user.IsVerified || user.VerificationCode != "" {
+		t.Errorf("user not verified: %+v", user)
+	}
+	if _, err := s.UserLogin(ctx, login); err != nil {
+		t.Errorf("UserLogin after verification: %v", err)
+	}
+}
+
+func TestVerifyEmailExpiredCode(t *testing.T) {
+	s, repo, _ := newTestService(t)
+	ctx := context.Background()
+	userID := signUp(t, s, "alice@example.com")
+
+	code, codeHash, err := auth.NewVerificationCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreVerificationCode(ctx, userID, codeHash, time.Now().Add(-time.Minute), repository.SendLimit{}); err != nil {
+		t.Fatalf("StoreVerificationCode: %v", err)
+	}
+
+	_, err = s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: code})
+	if !errors.Is(err, model.ErrCodeExpired) {
+		t.Errorf("VerifyEmail with an expired code = %v, want ErrCodeExpired", err)
+	}
+}
+
+func TestVerifyEmailAttemptLimit(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	userID := signUp(t, s, "alice@example.com")
+
+	for i := 0; i < MaxVerificationAttempts; i++ {
+		_, err := s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: "wrong"})
+		if !errors.Is(err, model.ErrInvalidCode) {
+			t.Fatalf("attempt %d = %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+
+	_, err := s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: mailer.lastCode(t, "alice@example.com")})
+	if !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Errorf("VerifyEmail after %d failures = %v, want ErrTooManyAttempts", MaxVerificationAttempts, err)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/notification"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+	"github.com/liju-github/FoodBuddyMicroserviceUser/repository"
+)
+
+// ResendVerification issues a fresh verification code for an unverified account.
+// The response is the same whether or not the email is registered, and also
+// when no code is sent because too many were sent to the account recently.
+func (s *UserService) ResendVerification(ctx context.Context, req *userExtPb.ResendVerificationRequest) (*userExtPb.ResendVerificationResponse, error) {
+	response := &userExtPb.ResendVerificationResponse{
+		Success: true,
+		Message: "If the account exists and is unverified, a new verification code has been sent.",
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil || user.IsVerified {
+		return response, nil
+	}
+
+	code, codeHash, err := auth.NewVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := repository.SendLimit{
+		Cooldown: VerificationResendCooldown,
+		MaxSends: MaxVerificationSends,
+		Window:   VerificationResendWindow,
+	}
+	err = s.repo.StoreVerificationCode(ctx, user.ID, codeHash, time.Now().Add(VerificationCodeExpiry), limit)
+	if errors.Is(err, model.ErrTooManyAttempts) {
+		s.logger.InfoContext(ctx, "verification resend throttled", "user_id", user.ID)
+		return response, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	if err := s.sendVerificationCode(ctx, user, code); err != nil {
+		s.logger.WarnContext(ctx, "failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	return response, nil
+}
+
+func (s *UserService) sendVerificationCode(ctx context.Context, user *model.User, code string) error {
+	return s.mailer.Send(ctx, notification.Message{
+		To:      user.Email,
+		Subject: "Verify your FoodBuddy account",
+		Body: fmt.Sprintf("Your FoodBuddy verification code is %s. It expires in %d minutes.",
+			code, int(VerificationCodeExpiry.Minutes())),
+	})
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userPb "github.com/liju-github/CentralisedFoodbuddyMicroserviceProto/User"
+
+	"github.com/liju-github/FoodBuddyMicroserviceUser/auth"
+	model "github.com/liju-github/FoodBuddyMicroserviceUser/models"
+	userExtPb "github.com/liju-github/FoodBuddyMicroserviceUser/proto/userext"
+)
+
+func TestResendVerification(t *testing.T) {
+	s, repo, mailer := newTestService(t)
+	ctx := context.Background()
+
+	// The first code went out before the cooldown, so a new one may be sent
+	oldCode, oldHash, err := auth.NewVerificationCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentAt := time.Now().Add(-2 * VerificationResendCooldown)
+	expiresAt := time.Now().Add(VerificationCodeExpiry)
+	err = repo.CreateUser(ctx, &model.User{
+		ID:                    "usr_1",
+		Email:                 "alice@example.com",
+		VerificationCode:      oldHash,
+		VerificationExpiresAt: &expiresAt,
+		VerificationSentAt:    &sentAt,
+		VerificationSends:     1,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := s.ResendVerification(ctx, &userExtPb.ResendVerificationRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("ResendVerification: %v", err)
+	}
+	newCode := mailer.lastCode(t, "alice@example.com")
+
+	_, err = s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: "usr_1", VerificationCode: oldCode})
+	if !errors.Is(err, model.ErrInvalidCode) {
+		t.Errorf("VerifyEmail with the replaced code = %v, want ErrInvalidCode", err)
+	}
+	if _, err := s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: "usr_1", VerificationCode: newCode}); err != nil {
+		t.Errorf("VerifyEmail with the resent code: %v", err)
+	}
+
+	// Unknown and verified accounts get the same answer and no email
+	for _, email := range []string{"nobody@example.com", "alice@example.com"} {
+		before := len(mailer.sent(email))
+		resp, err := s.ResendVerification(ctx, &userExtPb.ResendVerificationRequest{Email: email})
+		if err != nil || !resp.Success {
+			t.Errorf("ResendVerification(%s) = %v, %v", email, resp, err)
+		}
+		if len(mailer.sent(email)) != before {
+			t.Errorf("ResendVerification(%s) sent an email", email)
+		}
+	}
+}
+
+func TestResendVerificationKeepsAttemptLimit(t *testing.T) {
+	s, _, mailer := newTestService(t)
+	ctx := context.Background()
+	userID := signUp(t, s, "alice@example.com")
+
+	for i := 0; i < MaxVerificationAttempts; i++ {
+		s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: "wrong"})
+	}
+
+	// A resend right after signup is throttled, so it cannot reset the attempts
+	resp, err := s.ResendVerification(ctx, &userExtPb.ResendVerificationRequest{Email: "alice@example.com"})
+	if err != nil || !resp.Success {
+		t.Fatalf("throttled ResendVerification = %v, %v", resp, err)
+	}
+	if n := len(mailer.sent("alice@example.com")); n != 1 {
+		t.Errorf("%d emails sent, want only the signup email", n)
+	}
+
+	_, err = s.VerifyEmail(ctx, &userPb.EmailVerificationRequest{UserId: userID, VerificationCode: mailer.lastCode(t, "alice@example.com")})
+	if !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Errorf("VerifyEmail after a throttled resend = %v, want ErrTooManyAttempts", err)
+	}
+}